@@ -0,0 +1,67 @@
+package conformance
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bloodorangeio/reggie"
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var test00Base = func() {
+	g.Context(titleBase, func() {
+
+		g.Context(setupString, func() {
+			g.Specify("TLS handshake with the registry should succeed", func() {
+				RunOnlyIf(strings.HasPrefix(client.Config.Address, "https://"))
+				req := client.NewRequest(reggie.GET, "/v2/")
+				_, err := req.Execute(req.Method, req.URL)
+				if err != nil && isTLSError(err) {
+					tlsSetupFailure = fmt.Sprintf("TLS handshake with %s failed: %v", client.Config.Address, err)
+					g.AbortSuite(fmt.Sprintf("TLS setup failure: %s; check %s, %s, %s and %s",
+						tlsSetupFailure, envVarTLSCAFile, envVarTLSCertFile, envVarTLSKeyFile, envVarTLSServerName))
+				}
+			})
+		})
+
+		g.Context("Determining support", func() {
+			g.Specify("GET request to base endpoint without credentials should yield 200 or 401 response", func() {
+				req := client.NewRequest(reggie.GET, "/v2/")
+				resp, err := req.Execute(req.Method, req.URL)
+				if err != nil {
+					g.AbortSuite(fmt.Sprintf("cannot reach the registry at %s=%s: %v", envVarRootURL, client.Config.Address, err))
+				}
+				if v := resp.Header().Get("Docker-Distribution-API-Version"); v != "" {
+					apiVersion = v
+					g.AddReportEntry("Docker-Distribution-API-Version", v)
+				}
+				switch resp.StatusCode() {
+				case http.StatusOK:
+				case http.StatusUnauthorized:
+					Expect(resp.Header().Get("WWW-Authenticate")).ToNot(BeEmpty(),
+						"a 401 response to GET /v2/ must include a WWW-Authenticate challenge")
+				case http.StatusNotFound:
+					g.AbortSuite(fmt.Sprintf("the registry at %s=%s does not implement the OCI distribution API: GET /v2/ returned 404",
+						envVarRootURL, client.Config.Address))
+				default:
+					g.AbortSuite(fmt.Sprintf("unexpected response to GET /v2/ from the registry at %s=%s: %s",
+						envVarRootURL, client.Config.Address, resp.Status()))
+				}
+			})
+
+			g.Specify("GET request to base endpoint with credentials should yield 200 response", func() {
+				req := client.NewRequest(reggie.GET, "/v2/")
+				resp, err := client.Do(req)
+				if err != nil {
+					g.AbortSuite(fmt.Sprintf("cannot reach the registry at %s=%s: %v", envVarRootURL, client.Config.Address, err))
+				}
+				if resp.StatusCode() != http.StatusOK {
+					g.AbortSuite(fmt.Sprintf("GET /v2/ returned %s with the configured credentials; check %s, %s and %s",
+						resp.Status(), envVarUsername, envVarPassword, envVarAuthScope))
+				}
+			})
+		})
+	})
+}
@@ -1,8 +1,9 @@
 package conformance
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/bloodorangeio/reggie"
 	g "github.com/onsi/ginkgo/v2"
@@ -98,13 +99,10 @@ var test01Pull = func() {
 					BeNumerically("<", 300)))
 			})
 
-			g.Specify("Get tag name from environment", func() {
+			g.Specify("Get tag name from configuration", func() {
 				SkipIfDisabled(pull)
 				RunOnlyIfNot(runPullSetup)
-				tmp := os.Getenv(envVarTagName)
-				if tmp != "" {
-					tag = tmp
-				}
+				tag = runConfig.Workflows.Pull.TagName
 			})
 		})
 
@@ -148,6 +146,41 @@ var test01Pull = func() {
 			})
 		})
 
+		g.Context("Pull blobs with ranges", func() {
+			g.Specify("GET request with a single range should yield 206 with the range", func() {
+				SkipIfDisabled(pull)
+				RunOnlyIf(runPullSetup)
+				start, end := len(layerBlobData)/4, len(layerBlobData)/2
+				req := client.NewRequest(reggie.GET, "/v2/<name>/blobs/<digest>", reggie.WithDigest(layerBlobDigest)).
+					SetHeader("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				expectBlobRange(resp, layerBlobData, start, end)
+			})
+
+			g.Specify("GET request with an open range should yield 206 with the rest of the blob", func() {
+				SkipIfDisabled(pull)
+				RunOnlyIf(runPullSetup)
+				start := len(layerBlobData) / 2
+				req := client.NewRequest(reggie.GET, "/v2/<name>/blobs/<digest>", reggie.WithDigest(layerBlobDigest)).
+					SetHeader("Range", fmt.Sprintf("bytes=%d-", start))
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				expectBlobRange(resp, layerBlobData, start, len(layerBlobData)-1)
+			})
+
+			g.Specify("GET request with a suffix range should yield 206 with the end of the blob", func() {
+				SkipIfDisabled(pull)
+				RunOnlyIf(runPullSetup)
+				n := len(layerBlobData) / 3
+				req := client.NewRequest(reggie.GET, "/v2/<name>/blobs/<digest>", reggie.WithDigest(layerBlobDigest)).
+					SetHeader("Range", fmt.Sprintf("bytes=-%d", n))
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				expectBlobRange(resp, layerBlobData, len(layerBlobData)-n, len(layerBlobData)-1)
+			})
+		})
+
 		g.Context("Pull manifests", func() {
 			g.Specify("HEAD request to nonexistent manifest should return 404", func() {
 				SkipIfDisabled(pull)
@@ -367,3 +400,15 @@ var test01Pull = func() {
 		})
 	})
 }
+
+// expectBlobRange checks that resp holds the bytes start to end of blob in a
+// partial response. Range requests are optional, so the spec is skipped when
+// the registry answers with the whole blob instead.
+func expectBlobRange(resp *reggie.Response, blob []byte, start, end int) {
+	if resp.StatusCode() == http.StatusOK {
+		g.Skip("range requests are unsupported (optional): the registry returned the whole blob")
+	}
+	Expect(resp.StatusCode()).To(Equal(http.StatusPartialContent))
+	Expect(resp.Header().Get("Content-Range")).To(Equal(fmt.Sprintf("bytes %d-%d/%d", start, end, len(blob))))
+	Expect(bytes.Equal(resp.Body(), blob[start:end+1])).To(BeTrue())
+}
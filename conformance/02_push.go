@@ -1,14 +1,22 @@
 package conformance
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
-
-	"strconv"
+	"net/url"
+	"time"
 
 	"github.com/bloodorangeio/reggie"
 	g "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+	godigest "github.com/opencontainers/go-digest"
 )
 
 var test02Push = func() {
@@ -163,13 +171,10 @@ var test02Push = func() {
 				Expect(location).ToNot(BeEmpty())
 
 				// rebuild chunked blob if min size is above our chunk size
-				minSizeStr := resp.Header().Get("OCI-Chunk-Min-Length")
-				if minSizeStr != "" {
-					minSize, err := strconv.Atoi(minSizeStr)
-					Expect(err).To(BeNil())
-					if minSize > len(testBlobBChunk1) {
-						setupChunkedBlob(minSize*2 - 2)
-					}
+				minSize, err := chunkMinLength(resp)
+				Expect(err).To(BeNil())
+				if minSize > len(testBlobBChunk1) {
+					setupChunkedBlob(minSize*2 - 2)
 				}
 
 				req = client.NewRequest(reggie.PATCH, resp.GetRelativeLocation()).
@@ -256,6 +261,79 @@ var test02Push = func() {
 			})
 		})
 
+		g.Context("Blob Upload In Many Chunks", func() {
+			g.Specify("POST request should return 202 and any minimum chunk length", func() {
+				SkipIfDisabled(push)
+				req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/").
+					SetHeader("Content-Length", "0")
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusAccepted))
+				Expect(resp.Header().Get("Location")).ToNot(BeEmpty())
+				minSize, err := chunkMinLength(resp)
+				Expect(err).To(BeNil())
+				chunkSize := testChunkSize
+				if minSize > chunkSize {
+					chunkSize = minSize
+				}
+				testChunkedBlob = newChunkedBlob(chunkSize, testChunkCount, seed+3)
+				lastResponse = resp
+			})
+
+			g.Specify("PATCH requests with all chunks but the last should return 202 with a consistent range", func() {
+				SkipIfDisabled(push)
+				Expect(testChunkedBlob.Chunks).ToNot(BeEmpty())
+				chunks := testChunkedBlob.Chunks[:len(testChunkedBlob.Chunks)-1]
+				for i, chunk := range chunks {
+					req := client.NewRequest(reggie.PATCH, lastResponse.GetRelativeLocation()).
+						SetHeader("Content-Type", "application/octet-stream").
+						SetHeader("Content-Length", chunk.ContentLength).
+						SetHeader("Content-Range", chunk.Range).
+						SetBody(chunk.Content)
+					resp, err := client.Do(req)
+					Expect(err).To(BeNil())
+					Expect(resp.StatusCode()).To(Equal(http.StatusAccepted), "chunk %d", i+1)
+					Expect(resp.Header().Get("Location")).ToNot(BeEmpty(), "chunk %d", i+1)
+					uploaded := fmt.Sprintf("0-%d", (i+1)*len(chunks[0].Content)-1)
+					Expect(resp.Header().Get("Range")).To(Equal(uploaded), "chunk %d", i+1)
+
+					req = client.NewRequest(reggie.GET, resp.GetRelativeLocation())
+					resp, err = client.Do(req)
+					Expect(err).To(BeNil())
+					Expect(resp.StatusCode()).To(Equal(http.StatusNoContent), "status after chunk %d", i+1)
+					Expect(resp.Header().Get("Location")).ToNot(BeEmpty(), "status after chunk %d", i+1)
+					Expect(resp.Header().Get("Range")).To(Equal(uploaded), "status after chunk %d", i+1)
+					lastResponse = resp
+				}
+			})
+
+			g.Specify("PUT request with the last chunk should return 201", func() {
+				SkipIfDisabled(push)
+				Expect(testChunkedBlob.Chunks).ToNot(BeEmpty())
+				chunk := testChunkedBlob.Chunks[len(testChunkedBlob.Chunks)-1]
+				req := client.NewRequest(reggie.PUT, lastResponse.GetRelativeLocation()).
+					SetHeader("Content-Type", "application/octet-stream").
+					SetHeader("Content-Length", chunk.ContentLength).
+					SetHeader("Content-Range", chunk.Range).
+					SetQueryParam("digest", testChunkedBlob.Digest).
+					SetBody(chunk.Content)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+				Expect(resp.Header().Get("Location")).ToNot(BeEmpty())
+			})
+
+			g.Specify("GET request to the blob should return its content", func() {
+				SkipIfDisabled(push)
+				req := client.NewRequest(reggie.GET, "/v2/<name>/blobs/<digest>",
+					reggie.WithDigest(testChunkedBlob.Digest))
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+				Expect(bytes.Equal(resp.Body(), testChunkedBlob.Content)).To(BeTrue())
+			})
+		})
+
 		g.Context("Cross-Repository Blob Mount", func() {
 			g.Specify("Cross-mounting of a blob without the from argument should yield session id", func() {
 				SkipIfDisabled(push)
@@ -354,6 +432,7 @@ var test02Push = func() {
 
 			g.Specify("Registry should accept a manifest upload with no layers", func() {
 				SkipIfDisabled(push)
+				RunOnlyIfNot(runConfig.Workflows.Push.SkipEmptyLayer)
 				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
 					reggie.WithReference(emptyLayerTestTag)).
 					SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
@@ -380,6 +459,174 @@ var test02Push = func() {
 			})
 		})
 
+		g.Context("Manifest Size Limit", func() {
+			g.Specify("PUT request with a manifest of 4 megabytes should return 201", func() {
+				SkipIfDisabled(push)
+				m := newLargeManifest(testLargeManifestSize)
+				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(m.Digest)).
+					SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+					SetBody(m.Content)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+				largeManifestRefs = append(largeManifestRefs, m.Digest)
+
+				req = client.NewRequest(reggie.GET, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(m.Digest)).
+					SetHeader("Accept", "application/vnd.oci.image.manifest.v1+json")
+				resp, err = client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+				Expect(bytes.Equal(resp.Body(), m.Content)).To(BeTrue())
+			})
+
+			g.Specify("PUT request with an oversized manifest should return 413", func() {
+				SkipIfDisabled(push)
+				m := newLargeManifest(testOversizedManifestSize)
+				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(m.Digest)).
+					SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+					SetBody(m.Content)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				if resp.StatusCode() == http.StatusCreated {
+					largeManifestRefs = append(largeManifestRefs, m.Digest)
+				}
+				Expect(resp.StatusCode()).To(Equal(http.StatusRequestEntityTooLarge))
+				expectErrorCode(resp, endpoint.End7, v1.ErrorCodeManifestInvalid, v1.ErrorCodeSizeInvalid)
+				expectNotPulled(client.Config.DefaultName, m.Digest, v1.ErrorCodeManifestUnknown)
+			})
+		})
+
+		g.Context("Push Errors", func() {
+			g.Specify("PUT request with a digest not matching the blob should return DIGEST_INVALID", func() {
+				SkipIfDisabled(push)
+				req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/").
+					SetHeader("Content-Length", "0")
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusAccepted))
+				req = client.NewRequest(reggie.PUT, resp.GetRelativeLocation()).
+					SetHeader("Content-Type", "application/octet-stream").
+					SetHeader("Content-Length", testBlobALength).
+					SetQueryParam("digest", dummyDigest).
+					SetBody(testBlobA)
+				resp, err = client.Do(req)
+				Expect(err).To(BeNil())
+				expectErrorCode(resp, endpoint.End6, v1.ErrorCodeDigestInvalid)
+			})
+
+			g.Specify("PUT request with a Content-Length larger than the body should return SIZE_INVALID", func() {
+				SkipIfDisabled(push)
+				skipUnlessRawConn()
+				req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/").
+					SetHeader("Content-Length", "0")
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusAccepted))
+				u, err := url.Parse(resp.GetRelativeLocation())
+				Expect(err).To(BeNil())
+				q := u.Query()
+				q.Set("digest", testBlobADigest)
+				u.RawQuery = q.Encode()
+				status, body := putShortBody(u.String(), testBlobA)
+				expectError(status, body, endpoint.End6, v1.ErrorCodeSizeInvalid)
+			})
+
+			g.Specify("PUT request with a manifest referencing unknown blobs should return MANIFEST_BLOB_UNKNOWN", func() {
+				SkipIfDisabled(push)
+				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(godigest.FromBytes(unknownBlobManifestContent).String())).
+					SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+					SetBody(unknownBlobManifestContent)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				if resp.StatusCode() == http.StatusCreated {
+					req = client.NewRequest(reggie.DELETE, resp.GetRelativeLocation())
+					_, err = client.Do(req)
+					Expect(err).To(BeNil())
+					g.Skip("the registry accepts manifests referencing unknown blobs, which is allowed")
+				}
+				expectErrorCode(resp, endpoint.End7, v1.ErrorCodeManifestBlobUnknown)
+			})
+
+			g.Specify("PUT request with a Content-Type not matching the manifest mediaType should return MANIFEST_INVALID", func() {
+				SkipIfDisabled(push)
+				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(godigest.FromBytes(mismatchedTypeManifestContent).String())).
+					SetHeader("Content-Type", "application/vnd.oci.image.index.v1+json").
+					SetBody(mismatchedTypeManifestContent)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				if resp.StatusCode() == http.StatusCreated {
+					req = client.NewRequest(reggie.DELETE, resp.GetRelativeLocation())
+					_, err = client.Do(req)
+					Expect(err).To(BeNil())
+					g.Skip("the registry accepts a Content-Type not matching the manifest mediaType, which is allowed")
+				}
+				expectErrorCode(resp, endpoint.End7, v1.ErrorCodeManifestInvalid)
+			})
+		})
+
+		g.Context("Repository Names and Tags", func() {
+			for _, c := range nameCases() {
+				c := c
+				if c.Code == "" {
+					g.Specify("Push and pull to a name with "+c.Description+" should succeed", func() {
+						SkipIfDisabled(push)
+						name := client.Config.DefaultName + c.Value
+						g.DeferCleanup(deleteTestImage, name)
+						resp := pushTestImage(name, testTagName)
+						Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+						expectTestImage(name, testTagName)
+					})
+					continue
+				}
+				g.Specify("Push and pull to a name with "+c.Description+" should be rejected", func() {
+					SkipIfDisabled(push)
+					name := client.Config.DefaultName + c.Value
+					// removes what a registry not validating names stored
+					g.DeferCleanup(deleteTestImage, name)
+					req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/", reggie.WithName(name))
+					resp, err := client.Do(req)
+					Expect(err).To(BeNil())
+					expectErrorCode(resp, endpoint.End4a, c.Code)
+					req = client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+						reggie.WithName(name), reggie.WithReference(testTagName)).
+						SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+						SetBody(manifests[1].Content)
+					resp, err = client.Do(req)
+					Expect(err).To(BeNil())
+					expectErrorCode(resp, endpoint.End7, c.Code)
+					expectNotPulled(name, testTagName, c.Code)
+				})
+			}
+
+			for _, c := range tagCases() {
+				c := c
+				if c.Code == "" {
+					g.Specify("Push and pull of a tag with "+c.Description+" should succeed", func() {
+						SkipIfDisabled(push)
+						name := client.Config.DefaultName + tagRepository
+						g.DeferCleanup(deleteTestImage, name)
+						resp := pushTestImage(name, c.Value)
+						Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+						expectTestImage(name, c.Value)
+					})
+					continue
+				}
+				g.Specify("Push and pull of a tag with "+c.Description+" should be rejected", func() {
+					SkipIfDisabled(push)
+					name := client.Config.DefaultName + tagRepository
+					g.DeferCleanup(deleteTestImage, name)
+					resp := pushTestImage(name, c.Value)
+					expectErrorCode(resp, endpoint.End7, c.Code)
+					expectNotPulled(name, c.Value, c.Code)
+				})
+			}
+		})
+
 		g.Context("Teardown", func() {
 			if deleteManifestBeforeBlobs {
 				g.Specify("Delete manifest created in tests", func() {
@@ -395,6 +642,18 @@ var test02Push = func() {
 						),
 						Equal(http.StatusMethodNotAllowed),
 					))
+					for _, ref := range largeManifestRefs {
+						req = client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<reference>", reggie.WithReference(ref))
+						resp, err = client.Do(req)
+						Expect(err).To(BeNil())
+						Expect(resp.StatusCode()).To(SatisfyAny(
+							SatisfyAll(
+								BeNumerically(">=", 200),
+								BeNumerically("<", 300),
+							),
+							Equal(http.StatusMethodNotAllowed),
+						))
+					}
 					if emptyLayerManifestRef != "" {
 						req = client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<reference>", reggie.WithReference(emptyLayerManifestDigest))
 						resp, err = client.Do(req)
@@ -456,6 +715,18 @@ var test02Push = func() {
 						),
 						Equal(http.StatusMethodNotAllowed),
 					))
+					for _, ref := range largeManifestRefs {
+						req = client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<reference>", reggie.WithReference(ref))
+						resp, err = client.Do(req)
+						Expect(err).To(BeNil())
+						Expect(resp.StatusCode()).To(SatisfyAny(
+							SatisfyAll(
+								BeNumerically(">=", 200),
+								BeNumerically("<", 300),
+							),
+							Equal(http.StatusMethodNotAllowed),
+						))
+					}
 					if emptyLayerManifestRef != "" {
 						req = client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<reference>", reggie.WithReference(emptyLayerManifestDigest))
 						resp, err = client.Do(req)
@@ -473,3 +744,142 @@ var test02Push = func() {
 		})
 	})
 }
+
+// expectErrorCode checks that resp is an error of the endpoint id with one of
+// codes among the errors of its body. The status must be a failure status of
+// the endpoint, or the status expected with one of codes.
+func expectErrorCode(resp *reggie.Response, id endpoint.ID, codes ...v1.ErrorCode) {
+	expectError(resp.StatusCode(), resp.Body(), id, codes...)
+}
+
+// expectError is expectErrorCode for a response read without the client.
+func expectError(status int, body []byte, id endpoint.ID, codes ...v1.ErrorCode) {
+	failure := endpoint.MustLookup(id).IsFailure(status)
+	for _, code := range codes {
+		failure = failure || status == code.HTTPStatus()
+	}
+	Expect(failure).To(BeTrue(), "status %d is neither a failure status of %s nor the status of %v", status, id, codes)
+	var er v1.ErrorResponse
+	Expect(json.Unmarshal(body, &er)).To(Succeed())
+	Expect(er.Codes()).To(ContainElement(BeElementOf(codes)))
+}
+
+// skipUnlessRawConn skips a spec sending a request the HTTP client cannot
+// send. Such requests go over a plain connection to the registry, which
+// only reaches it without TLS, proxy or credentials.
+func skipUnlessRawConn() {
+	u, err := url.Parse(runConfig.RootURL)
+	Expect(err).To(BeNil())
+	if u.Scheme != "http" {
+		g.Skip("the request is only sent to registries served over plain HTTP")
+	}
+	if proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u}); err != nil || proxy != nil {
+		g.Skip("the request is not sent through a proxy")
+	}
+	if runConfig.Username != "" || runConfig.Password != "" {
+		g.Skip("the request is only sent to registries without authentication")
+	}
+}
+
+// putShortBody sends a PUT request for path over a new connection, with a
+// Content-Length one byte larger than body, and closes the sending side of
+// the connection after body. It returns the status and body of the response.
+func putShortBody(path string, body []byte) (int, []byte) {
+	u, err := url.Parse(runConfig.RootURL)
+	Expect(err).To(BeNil())
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	conn, err := net.DialTimeout("tcp", host, rawConnTimeout)
+	Expect(err).To(BeNil())
+	defer conn.Close()
+	Expect(conn.SetDeadline(time.Now().Add(rawConnTimeout))).To(Succeed())
+	_, err = fmt.Fprintf(conn, "PUT %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\n"+
+		"Content-Type: application/octet-stream\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+		path, u.Host, userAgent, len(body)+1)
+	Expect(err).To(BeNil())
+	_, err = conn.Write(body)
+	Expect(err).To(BeNil())
+	Expect(conn.(*net.TCPConn).CloseWrite()).To(Succeed())
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	Expect(err).To(BeNil(), "the registry closed the connection without a response")
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	Expect(err).To(BeNil())
+	return resp.StatusCode, content
+}
+
+// pushTestImage pushes the blobs of manifests[1] to the repository name and
+// returns the response to pushing the manifest with tag.
+func pushTestImage(name, tag string) *reggie.Response {
+	for _, b := range []TestBlob{configs[1], {Content: layerBlobData, ContentLength: layerBlobContentLength, Digest: layerBlobDigest}} {
+		req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/", reggie.WithName(name))
+		resp, err := client.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode()).To(Equal(http.StatusAccepted))
+		req = client.NewRequest(reggie.PUT, resp.GetRelativeLocation()).
+			SetQueryParam("digest", b.Digest).
+			SetHeader("Content-Type", "application/octet-stream").
+			SetHeader("Content-Length", b.ContentLength).
+			SetBody(b.Content)
+		resp, err = client.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+	}
+	req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+		reggie.WithName(name), reggie.WithReference(tag)).
+		SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+		SetBody(manifests[1].Content)
+	resp, err := client.Do(req)
+	Expect(err).To(BeNil())
+	return resp
+}
+
+// expectTestImage checks that tag in the repository name holds manifests[1].
+func expectTestImage(name, tag string) {
+	req := client.NewRequest(reggie.GET, "/v2/<name>/manifests/<reference>",
+		reggie.WithName(name), reggie.WithReference(tag)).
+		SetHeader("Accept", "application/vnd.oci.image.manifest.v1+json")
+	resp, err := client.Do(req)
+	Expect(err).To(BeNil())
+	Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+	Expect(bytes.Equal(resp.Body(), manifests[1].Content)).To(BeTrue())
+}
+
+// expectNotPulled checks that pulling tag from the repository name fails,
+// either with one of codes or because nothing was pushed there.
+func expectNotPulled(name, tag string, codes ...v1.ErrorCode) {
+	req := client.NewRequest(reggie.GET, "/v2/<name>/manifests/<reference>",
+		reggie.WithName(name), reggie.WithReference(tag)).
+		SetHeader("Accept", "application/vnd.oci.image.manifest.v1+json")
+	resp, err := client.Do(req)
+	Expect(err).To(BeNil())
+	if resp.StatusCode() != http.StatusNotFound {
+		expectErrorCode(resp, endpoint.End3, codes...)
+	}
+}
+
+// deleteTestImage deletes manifests[1] and its blobs from the repository
+// name, for registries supporting deletion. Objects that were never stored,
+// and names the registry rejects, are ignored.
+func deleteTestImage(name string) {
+	for _, path := range []string{
+		"/v2/<name>/manifests/" + manifests[1].Digest,
+		"/v2/<name>/blobs/" + configs[1].Digest,
+		"/v2/<name>/blobs/" + layerBlobDigest,
+	} {
+		req := client.NewRequest(reggie.DELETE, path, reggie.WithName(name))
+		resp, err := client.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode()).To(SatisfyAny(
+			SatisfyAll(
+				BeNumerically(">=", 200),
+				BeNumerically("<", 300),
+			),
+			Equal(http.StatusBadRequest),
+			Equal(http.StatusNotFound),
+			Equal(http.StatusMethodNotAllowed),
+		))
+	}
+}
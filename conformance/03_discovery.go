@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,6 +11,8 @@ import (
 	"github.com/bloodorangeio/reggie"
 	g "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/opencontainers/distribution-spec/specs-go/referrers"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
 	godigest "github.com/opencontainers/go-digest"
 )
 
@@ -20,6 +21,46 @@ var test03ContentDiscovery = func() {
 
 		var numTags = 4
 		var tagList []string
+		var subjectFallbackDigests []string
+
+		// checkSubjectHeader checks the response to the push of a manifest with
+		// a subject. A registry that omits the OCI-Subject header leaves the
+		// client responsible for the referrers tag schema fallback, which is
+		// then updated as described in /spec.md#pushing-manifests-with-subject.
+		checkSubjectHeader := func(resp *reggie.Response, subject, mediaType string, content []byte) {
+			behaviour := subjectBehaviourFallback
+			if resp.Header().Get("OCI-Subject") != "" {
+				behaviour = subjectBehaviourHeader
+			}
+			if subjectBehaviour == "" {
+				subjectBehaviour = behaviour
+				g.AddReportEntry("Subject processing", behaviour)
+			}
+			Expect(behaviour).To(Equal(subjectBehaviour),
+				"the registry returned the OCI-Subject header for some manifests with a subject but not for others")
+			if behaviour == subjectBehaviourHeader {
+				Expect(resp.Header().Get("OCI-Subject")).To(Equal(subject))
+				return
+			}
+
+			// a registry supporting the referrers API must return the header
+			req := client.NewRequest(reggie.GET, "/v2/<name>/referrers/<digest>",
+				reggie.WithDigest(subject))
+			resp, err := client.Do(req)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode()).ToNot(Equal(http.StatusOK),
+				"the registry supports the referrers API but did not return the OCI-Subject header")
+
+			desc, err := referrerDescriptor(mediaType, content)
+			Expect(err).To(BeNil())
+			index, err := newReferrersClient().Update(subject, func(index *v1.ReferrersResponse) bool {
+				return referrers.Add(index, desc)
+			})
+			Expect(err).To(BeNil())
+			body, err := json.Marshal(index)
+			Expect(err).To(BeNil())
+			subjectFallbackDigests = append(subjectFallbackDigests, godigest.FromBytes(body).String())
+		}
 
 		g.Context("Setup", func() {
 			g.Specify("Populate registry with test blob", func() {
@@ -84,7 +125,7 @@ var test03ContentDiscovery = func() {
 			g.Specify("Populate registry with test tags (no push)", func() {
 				SkipIfDisabled(contentDiscovery)
 				RunOnlyIfNot(runContentDiscoverySetup)
-				tagList = strings.Split(os.Getenv(envVarTagList), ",")
+				tagList = runConfig.Workflows.ContentDiscovery.TagList
 			})
 
 			g.Specify("References setup", func() {
@@ -93,12 +134,12 @@ var test03ContentDiscovery = func() {
 
 				// Populate registry with empty JSON blob
 				// validate expected empty JSON blob digest
-				Expect(emptyJSONDescriptor.Digest).To(Equal(godigest.Digest("sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a")))
+				Expect(emptyJSONDescriptor.Digest).To(Equal("sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"))
 				req := client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/")
 				resp, err := client.Do(req)
 				Expect(err).To(BeNil())
 				req = client.NewRequest(reggie.PUT, resp.GetRelativeLocation()).
-					SetQueryParam("digest", emptyJSONDescriptor.Digest.String()).
+					SetQueryParam("digest", emptyJSONDescriptor.Digest).
 					SetHeader("Content-Type", "application/octet-stream").
 					SetHeader("Content-Length", fmt.Sprintf("%d", emptyJSONDescriptor.Size)).
 					SetBody(emptyJSONBlob)
@@ -133,7 +174,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[4].Digest))
+				checkSubjectHeader(resp, manifests[4].Digest, "application/vnd.oci.image.manifest.v1+json", refsManifestAConfigArtifactContent)
 
 				// Populate registry with test references manifest (ArtifactType, config.MediaType = emptyJSON)
 				req = client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
@@ -145,7 +186,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[4].Digest))
+				checkSubjectHeader(resp, manifests[4].Digest, "application/vnd.oci.image.manifest.v1+json", refsManifestALayerArtifactContent)
 
 				// Populate registry with test index manifest
 				req = client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
@@ -157,7 +198,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[4].Digest))
+				checkSubjectHeader(resp, manifests[4].Digest, v1.MediaTypeImageIndex, refsIndexArtifactContent)
 
 				// Populate registry with test blob
 				req = client.NewRequest(reggie.POST, "/v2/<name>/blobs/uploads/")
@@ -226,7 +267,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[4].Digest))
+				checkSubjectHeader(resp, manifests[4].Digest, "application/vnd.oci.image.manifest.v1+json", refsManifestBConfigArtifactContent)
 
 				// Populate registry with test references manifest (ArtifactType, config.MediaType = emptyJSON)
 				req = client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
@@ -238,7 +279,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[4].Digest))
+				checkSubjectHeader(resp, manifests[4].Digest, "application/vnd.oci.image.manifest.v1+json", refsManifestBLayerArtifactContent)
 
 				// Populate registry with test references manifest to a non-existent subject
 				req = client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
@@ -250,7 +291,7 @@ var test03ContentDiscovery = func() {
 				Expect(resp.StatusCode()).To(SatisfyAll(
 					BeNumerically(">=", 200),
 					BeNumerically("<", 300)))
-				Expect(resp.Header().Get("OCI-Subject")).To(Equal(manifests[3].Digest))
+				checkSubjectHeader(resp, manifests[3].Digest, "application/vnd.oci.image.manifest.v1+json", refsManifestCLayerArtifactContent)
 			})
 		})
 
@@ -309,17 +350,43 @@ var test03ContentDiscovery = func() {
 		})
 
 		g.Context("Test content discovery endpoints (listing references)", func() {
+			// skipIfReferrersUnavailable skips the referrers API specs for
+			// registries that did not return the OCI-Subject header and do
+			// not serve the referrers API.
+			skipIfReferrersUnavailable := func(resp *reggie.Response) {
+				if subjectBehaviour == subjectBehaviourFallback && resp.StatusCode() == http.StatusNotFound {
+					g.Skip("the referrers API is unavailable and the registry relies on the referrers tag schema")
+				}
+			}
+
+			// getFallbackIndex returns the referrers tag schema fallback index
+			// of subject.
+			getFallbackIndex := func(subject string) *v1.ReferrersResponse {
+				tag, err := referrers.Tag(subject)
+				Expect(err).To(BeNil())
+				req := client.NewRequest(reggie.GET, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(tag)).
+					SetHeader("Accept", v1.MediaTypeImageIndex)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+				Expect(resp.Header().Get("Content-Type")).To(Equal(v1.MediaTypeImageIndex))
+				index, err := parseReferrersResponse(resp)
+				Expect(err).To(BeNil())
+				return index
+			}
+
 			g.Specify("GET request to nonexistent blob should result in empty 200 response", func() {
 				SkipIfDisabled(contentDiscovery)
 				req := client.NewRequest(reggie.GET, "/v2/<name>/referrers/<digest>",
 					reggie.WithDigest(dummyDigest))
 				resp, err := client.Do(req)
 				Expect(err).To(BeNil())
+				skipIfReferrersUnavailable(resp)
 				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
 				Expect(resp.Header().Get("Content-Type")).To(Equal("application/vnd.oci.image.index.v1+json"))
 
-				var index index
-				err = json.Unmarshal(resp.Body(), &index)
+				index, err := parseReferrersResponse(resp)
 				Expect(err).To(BeNil())
 				Expect(len(index.Manifests)).To(BeZero())
 			})
@@ -330,17 +397,17 @@ var test03ContentDiscovery = func() {
 					reggie.WithDigest(manifests[4].Digest))
 				resp, err := client.Do(req)
 				Expect(err).To(BeNil())
+				skipIfReferrersUnavailable(resp)
 				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
 				Expect(resp.Header().Get("Content-Type")).To(Equal("application/vnd.oci.image.index.v1+json"))
 
-				var index index
-				err = json.Unmarshal(resp.Body(), &index)
+				index, err := parseReferrersResponse(resp)
 				Expect(err).To(BeNil())
 				Expect(len(index.Manifests)).To(Equal(5))
 				Expect(index.Manifests[0].Digest).ToNot(Equal(index.Manifests[1].Digest))
 				for i := 0; i < len(index.Manifests); i++ {
 					Expect(len(index.Manifests[i].Annotations)).To(Equal(1))
-					Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest.String()]))
+					Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest]))
 				}
 			})
 
@@ -351,27 +418,28 @@ var test03ContentDiscovery = func() {
 					SetQueryParam("artifactType", testRefArtifactTypeA)
 				resp, err := client.Do(req)
 				Expect(err).To(BeNil())
+				skipIfReferrersUnavailable(resp)
 				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
 				Expect(resp.Header().Get("Content-Type")).To(Equal("application/vnd.oci.image.index.v1+json"))
 
-				var index index
-				err = json.Unmarshal(resp.Body(), &index)
+				index, err := parseReferrersResponse(resp)
 				Expect(err).To(BeNil())
 
 				// also check resp header "OCI-Filters-Applied: artifactType" denoting that an artifactType filter was applied
-				if resp.Header().Get("OCI-Filters-Applied") != "" {
+				if resp.Header().Get(v1.HeaderFiltersApplied) != "" {
 					Expect(len(index.Manifests)).To(Equal(2))
-					Expect(resp.Header().Get("OCI-Filters-Applied")).To(Equal(artifactTypeFilter))
+					Expect(index.FiltersApplied).To(Equal([]string{v1.FilterArtifactType}))
 					for i := 0; i < len(index.Manifests); i++ {
 						Expect(len(index.Manifests[i].Annotations)).To(Equal(1))
-						Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest.String()]))
+						Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest]))
 					}
 				} else {
 					Expect(len(index.Manifests)).To(Equal(5))
 					for i := 0; i < len(index.Manifests); i++ {
 						Expect(len(index.Manifests[i].Annotations)).To(Equal(1))
-						Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest.String()]))
+						Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest]))
 					}
+					Expect(len(index.FilterArtifactType(testRefArtifactTypeA).Manifests)).To(Equal(2))
 					Warn("filtering by artifact-type is not implemented")
 				}
 			})
@@ -382,14 +450,113 @@ var test03ContentDiscovery = func() {
 					reggie.WithDigest(manifests[3].Digest))
 				resp, err := client.Do(req)
 				Expect(err).To(BeNil())
+				skipIfReferrersUnavailable(resp)
 				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
 				Expect(resp.Header().Get("Content-Type")).To(Equal("application/vnd.oci.image.index.v1+json"))
 
-				var index index
-				err = json.Unmarshal(resp.Body(), &index)
+				index, err := parseReferrersResponse(resp)
 				Expect(err).To(BeNil())
 				Expect(len(index.Manifests)).To(Equal(1))
-				Expect(index.Manifests[0].Digest.String()).To(Equal(refsManifestCLayerArtifactDigest))
+				Expect(index.Manifests[0].Digest).To(Equal(refsManifestCLayerArtifactDigest))
+			})
+
+			g.Specify("GET referrers tag of existing manifest should yield the fallback index", func() {
+				SkipIfDisabled(contentDiscovery)
+				RunOnlyIf(subjectBehaviour == subjectBehaviourFallback)
+				index := getFallbackIndex(manifests[4].Digest)
+				Expect(len(index.Manifests)).To(Equal(5))
+				for i := 0; i < len(index.Manifests); i++ {
+					Expect(len(index.Manifests[i].Annotations)).To(Equal(1))
+					Expect(index.Manifests[i].Annotations[testAnnotationKey]).To(Equal(testAnnotationValues[index.Manifests[i].Digest]))
+				}
+			})
+
+			g.Specify("GET referrers tag of missing manifest should yield the fallback index", func() {
+				SkipIfDisabled(contentDiscovery)
+				RunOnlyIf(subjectBehaviour == subjectBehaviourFallback)
+				index := getFallbackIndex(manifests[3].Digest)
+				Expect(len(index.Manifests)).To(Equal(1))
+				Expect(index.Manifests[0].Digest).To(Equal(refsManifestCLayerArtifactDigest))
+			})
+		})
+
+		g.Context("Test referrers tag schema fallback", func() {
+			var referrersTag string
+			var fallbackIndex *v1.ReferrersResponse
+			var fallbackIndexDigests []string
+
+			// pushFallbackIndex pushes fallbackIndex to the referrers tag and
+			// checks it is served back unchanged.
+			pushFallbackIndex := func() {
+				body, err := json.Marshal(fallbackIndex)
+				Expect(err).To(BeNil())
+				req := client.NewRequest(reggie.PUT, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(referrersTag)).
+					SetHeader("Content-Type", v1.MediaTypeImageIndex).
+					SetBody(body)
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusCreated))
+				fallbackIndexDigests = append(fallbackIndexDigests, godigest.FromBytes(body).String())
+
+				req = client.NewRequest(reggie.GET, "/v2/<name>/manifests/<reference>",
+					reggie.WithReference(referrersTag)).
+					SetHeader("Accept", v1.MediaTypeImageIndex)
+				resp, err = client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+				Expect(resp.Header().Get("Content-Type")).To(Equal(v1.MediaTypeImageIndex))
+				Expect(resp.Body()).To(Equal(body))
+				if d := resp.Header().Get("Docker-Content-Digest"); d != "" {
+					Expect(d).To(Equal(godigest.FromBytes(body).String()))
+				}
+			}
+
+			g.BeforeEach(func() {
+				SkipIfDisabled(contentDiscovery)
+				RunOnlyIf(runContentDiscoverySetup)
+				RunOnlyIf(runReferrersTagSchemaTest)
+			})
+
+			g.Specify("PUT fallback index to the referrers tag of a subject should yield 201", func() {
+				var err error
+				referrersTag, err = referrers.Tag(fallbackSubjectDigest)
+				Expect(err).To(BeNil())
+				desc, err := referrerDescriptor("application/vnd.oci.image.manifest.v1+json", refsManifestAConfigArtifactContent)
+				Expect(err).To(BeNil())
+				fallbackIndex = v1.NewReferrersResponse()
+				referrers.Add(fallbackIndex, desc)
+				pushFallbackIndex()
+			})
+
+			g.Specify("PUT updated fallback index should replace the one served by the referrers tag", func() {
+				Expect(fallbackIndex).ToNot(BeNil())
+				desc, err := referrerDescriptor("application/vnd.oci.image.manifest.v1+json", refsManifestBConfigArtifactContent)
+				Expect(err).To(BeNil())
+				Expect(referrers.Add(fallbackIndex, desc)).To(BeTrue())
+				pushFallbackIndex()
+
+				req := client.NewRequest(reggie.GET, "/v2/<name>/tags/list")
+				resp, err := client.Do(req)
+				Expect(err).To(BeNil())
+				Expect(resp.StatusCode()).To(Equal(http.StatusOK))
+				Expect(getTagList(resp)).To(ContainElement(referrersTag))
+			})
+
+			g.Specify("Delete fallback index", func() {
+				for _, ref := range fallbackIndexDigests {
+					req := client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<digest>", reggie.WithDigest(ref))
+					resp, err := client.Do(req)
+					Expect(err).To(BeNil())
+					Expect(resp.StatusCode()).To(SatisfyAny(
+						SatisfyAll(
+							BeNumerically(">=", 200),
+							BeNumerically("<", 300),
+						),
+						Equal(http.StatusNotFound),
+						Equal(http.StatusMethodNotAllowed),
+					))
+				}
 			})
 		})
 
@@ -398,7 +565,9 @@ var test03ContentDiscovery = func() {
 				g.Specify("Delete created manifest & associated tags", func() {
 					SkipIfDisabled(contentDiscovery)
 					RunOnlyIf(runContentDiscoverySetup)
-					references := []string{
+					// the fallback indexes list the manifests below, delete them first
+					references := append([]string{}, subjectFallbackDigests...)
+					references = append(references,
 						refsIndexArtifactDigest,
 						manifests[2].Digest,
 						manifests[4].Digest,
@@ -407,7 +576,7 @@ var test03ContentDiscovery = func() {
 						refsManifestBConfigArtifactDigest,
 						refsManifestBLayerArtifactDigest,
 						refsManifestCLayerArtifactDigest,
-					}
+					)
 					for _, ref := range references {
 						req := client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<digest>", reggie.WithDigest(ref))
 						resp, err := client.Do(req)
@@ -460,7 +629,9 @@ var test03ContentDiscovery = func() {
 				g.Specify("Delete created manifest & associated tags", func() {
 					SkipIfDisabled(contentDiscovery)
 					RunOnlyIf(runContentDiscoverySetup)
-					references := []string{
+					// the fallback indexes list the manifests below, delete them first
+					references := append([]string{}, subjectFallbackDigests...)
+					references = append(references,
 						refsIndexArtifactDigest,
 						manifests[2].Digest,
 						manifests[4].Digest,
@@ -469,7 +640,7 @@ var test03ContentDiscovery = func() {
 						refsManifestBConfigArtifactDigest,
 						refsManifestBLayerArtifactDigest,
 						refsManifestCLayerArtifactDigest,
-					}
+					)
 					for _, ref := range references {
 						req := client.NewRequest(reggie.DELETE, "/v2/<name>/manifests/<digest>", reggie.WithDigest(ref))
 						resp, err := client.Do(req)
@@ -534,7 +705,7 @@ var test03ContentDiscovery = func() {
 				deleteReq(req)
 
 				// Delete empty JSON blob created in setup
-				req = client.NewRequest(reggie.DELETE, "/v2/<name>/blobs/<digest>", reggie.WithDigest(emptyJSONDescriptor.Digest.String()))
+				req = client.NewRequest(reggie.DELETE, "/v2/<name>/blobs/<digest>", reggie.WithDigest(emptyJSONDescriptor.Digest))
 				deleteReq(req)
 
 				if !deleteManifestBeforeBlobs {
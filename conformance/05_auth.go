@@ -0,0 +1,110 @@
+package conformance
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bloodorangeio/reggie"
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/distribution-spec/conformance/auth"
+)
+
+var test05Authentication = func() {
+	g.Context(titleAuthentication, func() {
+
+		var challenge *auth.Challenge
+
+		// send sends a request without the credentials handling of the
+		// client, presenting token when set.
+		send := func(method, path, token string) (int, []byte, http.Header) {
+			req := client.NewRequest(method, path)
+			if token != "" {
+				req.SetHeader("Authorization", "Bearer "+token)
+			}
+			resp, err := req.Execute(req.Method, req.URL)
+			Expect(err).To(BeNil())
+			return resp.StatusCode(), resp.Body(), resp.Header()
+		}
+
+		// checkChallenge checks the challenge returned for an unauthenticated
+		// request asks for a scope granting actions.
+		checkChallenge := func(method, path string, actions ...string) {
+			status, _, header := send(method, path, "")
+			if status != http.StatusUnauthorized {
+				g.Skip(fmt.Sprintf("the registry answered %s %s without credentials with %d", method, path, status))
+			}
+			challenges, err := auth.ParseChallenges(header.Values("WWW-Authenticate"))
+			Expect(err).To(BeNil())
+			Expect(challenges).ToNot(BeEmpty(), "a 401 response must include a WWW-Authenticate challenge")
+			for i := range challenges {
+				if challenges[i].Scheme == "bearer" {
+					Expect(auth.CheckChallenge(challenges[i], client.Config.DefaultName, actions...)).To(Succeed())
+					challenge = &challenges[i]
+					return
+				}
+			}
+			g.Skip(fmt.Sprintf("the registry does not use bearer token authentication: %v", header.Values("WWW-Authenticate")))
+		}
+
+		// fetchToken requests a token for the test namespace granting actions.
+		fetchToken := func(actions string) string {
+			if challenge == nil {
+				g.Skip("no bearer challenge was received")
+			}
+			token, err := auth.FetchToken(client.GetClient(), *challenge,
+				fmt.Sprintf("repository:%s:%s", client.Config.DefaultName, actions),
+				client.Config.Username, client.Config.Password)
+			Expect(err).To(BeNil())
+			return token
+		}
+
+		g.Context("Challenges", func() {
+			g.Specify("Unauthenticated pull should be challenged for a pull scope", func() {
+				SkipIfDisabled(authentication)
+				checkChallenge(reggie.GET, "/v2/<name>/tags/list", "pull")
+			})
+
+			g.Specify("Unauthenticated push should be challenged for a push scope", func() {
+				SkipIfDisabled(authentication)
+				checkChallenge(reggie.POST, "/v2/<name>/blobs/uploads/", "push")
+			})
+
+			g.Specify("Unauthenticated delete should be challenged for a delete scope", func() {
+				SkipIfDisabled(authentication)
+				checkChallenge(reggie.DELETE, "/v2/<name>/manifests/"+dummyDigest, "delete")
+			})
+		})
+
+		g.Context("Tokens", func() {
+			g.Specify("Token scoped to pull should be accepted for pull", func() {
+				SkipIfDisabled(authentication)
+				status, _, _ := send(reggie.GET, "/v2/<name>/tags/list", fetchToken("pull"))
+				Expect(status).To(SatisfyAny(
+					Equal(http.StatusOK),
+					Equal(http.StatusNotFound),
+				))
+			})
+
+			g.Specify("Token scoped to pull should be rejected for push with UNAUTHORIZED or DENIED", func() {
+				SkipIfDisabled(authentication)
+				status, body, _ := send(reggie.POST, "/v2/<name>/blobs/uploads/", fetchToken("pull"))
+				Expect(auth.CheckRejected(status, body)).To(Succeed())
+			})
+
+			g.Specify("Tampered token should be rejected with UNAUTHORIZED or DENIED", func() {
+				SkipIfDisabled(authentication)
+				status, body, _ := send(reggie.GET, "/v2/<name>/tags/list", fetchToken("pull")+"x")
+				Expect(auth.CheckRejected(status, body)).To(Succeed())
+			})
+
+			g.Specify("Expired token should be rejected with UNAUTHORIZED or DENIED", func() {
+				SkipIfDisabled(authentication)
+				expiredToken := runConfig.Workflows.Authentication.ExpiredToken
+				RunOnlyIf(expiredToken != "")
+				status, body, _ := send(reggie.GET, "/v2/<name>/tags/list", expiredToken)
+				Expect(auth.CheckRejected(status, body)).To(Succeed())
+			})
+		})
+	})
+}
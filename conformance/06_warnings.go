@@ -0,0 +1,64 @@
+package conformance
+
+import (
+	"fmt"
+
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/distribution-spec/specs-go/warning"
+)
+
+var test06Warnings = func() {
+	g.Context(titleWarnings, func() {
+
+		g.Context("Warning headers", func() {
+			g.BeforeEach(func() {
+				SkipIfDisabled(warnings)
+				if len(receivedWarnings.all()) == 0 {
+					g.GinkgoWriter.Println("no Warning headers were received during the run")
+				}
+			})
+
+			g.Specify("Warning headers are a list of warn-code, warn-agent and quoted warn-text", func() {
+				var problems []string
+				for _, r := range receivedWarnings.all() {
+					for _, v := range r.Values {
+						if _, err := warning.Parse(v); err != nil {
+							problems = append(problems, fmt.Sprintf("%s %s: %v", r.Method, r.URL, err))
+						}
+					}
+				}
+				Expect(problems).To(BeEmpty())
+			})
+
+			g.Specify("Warnings use the warn-code 299 and the warn-agent -, without a warn-date", func() {
+				var problems []string
+				for _, r := range receivedWarnings.all() {
+					for _, v := range r.Values {
+						ws, _ := warning.Parse(v)
+						for _, w := range ws {
+							if err := w.Validate(); err != nil {
+								problems = append(problems, fmt.Sprintf("%s %s: %v", r.Method, r.URL, err))
+							}
+						}
+					}
+				}
+				Expect(problems).To(BeEmpty())
+			})
+
+			g.Specify("Warning headers of a response do not exceed 4096 bytes combined", func() {
+				var problems []string
+				for _, r := range receivedWarnings.all() {
+					size := 0
+					for _, v := range r.Values {
+						size += len(v)
+					}
+					if size > warning.MaxSize {
+						problems = append(problems, fmt.Sprintf("%s %s: %d bytes", r.Method, r.URL, size))
+					}
+				}
+				Expect(problems).To(BeEmpty())
+			})
+		})
+	})
+}
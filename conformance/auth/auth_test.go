@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChallenges(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []Challenge
+		wantErr bool
+	}{
+		{
+			in: []string{`Bearer realm="https://auth.example.org/token",service="registry.example.org",scope="repository:a/b:pull,push"`},
+			want: []Challenge{{Scheme: "bearer", Params: map[string]string{
+				"realm": "https://auth.example.org/token", "service": "registry.example.org", "scope": "repository:a/b:pull,push",
+			}}},
+		},
+		{
+			in: []string{`Negotiate, Basic realm="r, with comma", Bearer realm=https://a/token`},
+			want: []Challenge{
+				{Scheme: "negotiate", Params: map[string]string{}},
+				{Scheme: "basic", Params: map[string]string{"realm": "r, with comma"}},
+				{Scheme: "bearer", Params: map[string]string{"realm": "https://a/token"}},
+			},
+		},
+		{in: []string{`Bearer realm="unterminated`}, wantErr: true},
+		{in: []string{`Bearer =x`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChallenges(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChallenges(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseChallenges(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestWorkflow runs the checks of the Authentication workflow against a
+// registry stub protected by the stand-in token server.
+func TestWorkflow(t *testing.T) {
+	ts := NewTokenServer("registry.test")
+	ts.Users = map[string]string{"user": "pass"}
+	tokenSrv := httptest.NewServer(ts)
+	defer tokenSrv.Close()
+	registry := httptest.NewServer(ts.Protect(tokenSrv.URL, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+	defer registry.Close()
+
+	do := func(method, path, token string) (int, []byte, http.Header) {
+		req, _ := http.NewRequest(method, registry.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := registry.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body, resp.Header
+	}
+
+	const name = "myorg/myrepo"
+	var challenge Challenge
+	for _, op := range []struct {
+		method, path string
+		actions      []string
+	}{
+		{http.MethodGet, "/v2/" + name + "/tags/list", []string{"pull"}},
+		{http.MethodPost, "/v2/" + name + "/blobs/uploads/", []string{"push"}},
+		{http.MethodDelete, "/v2/" + name + "/manifests/sha256:" + "0000000000000000000000000000000000000000000000000000000000000000", []string{"delete"}},
+	} {
+		status, _, h := do(op.method, op.path, "")
+		challenges, err := ParseChallenges(h.Values("WWW-Authenticate"))
+		if status != http.StatusUnauthorized || err != nil || len(challenges) != 1 {
+			t.Fatalf("%s %s: got %d with challenges %v, %v", op.method, op.path, status, challenges, err)
+		}
+		if err := CheckChallenge(challenges[0], name, op.actions...); err != nil {
+			t.Errorf("%s %s: %v", op.method, op.path, err)
+		}
+		challenge = challenges[0]
+	}
+	if err := CheckChallenge(challenge, name, "pull", "push"); err == nil {
+		t.Errorf("CheckChallenge() should fail for actions the scope does not grant")
+	}
+
+	if _, err := FetchToken(tokenSrv.Client(), challenge, "repository:"+name+":pull", "user", "wrong"); err == nil {
+		t.Errorf("FetchToken() with wrong password should fail")
+	}
+	pullToken, err := FetchToken(tokenSrv.Client(), challenge, "repository:"+name+":pull", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushToken, err := FetchToken(tokenSrv.Client(), challenge, "repository:"+name+":pull,push", "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := do(http.MethodPost, "/v2/"+name+"/blobs/uploads/", pushToken); status != http.StatusAccepted {
+		t.Errorf("push with push token: got %d", status)
+	}
+
+	expired := ts.Issue(-time.Minute, Scope{Type: "repository", Name: name, Actions: []string{"pull"}})
+	for _, tt := range []struct {
+		desc, method, path, token string
+	}{
+		{"wrong scope", http.MethodPost, "/v2/" + name + "/blobs/uploads/", pullToken},
+		{"other repository", http.MethodGet, "/v2/other/tags/list", pullToken},
+		{"expired", http.MethodGet, "/v2/" + name + "/tags/list", expired},
+		{"tampered", http.MethodGet, "/v2/" + name + "/tags/list", pullToken + "x"},
+	} {
+		status, body, _ := do(tt.method, tt.path, tt.token)
+		if err := CheckRejected(status, body); err != nil {
+			t.Errorf("%s token: %v", tt.desc, err)
+		}
+	}
+	if err := CheckRejected(http.StatusAccepted, nil); err == nil {
+		t.Errorf("CheckRejected() should fail for an accepted request")
+	}
+}
@@ -0,0 +1,244 @@
+// Package auth checks the bearer token authentication of a registry, and
+// provides a stand-in token server to exercise those checks offline.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+// Doer sends HTTP requests. *http.Client implements Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Challenge is a single challenge of a WWW-Authenticate header.
+type Challenge struct {
+	// Scheme is the lowercase authentication scheme, such as "bearer".
+	Scheme string
+
+	// Params holds the auth-params, keyed by lowercase name.
+	Params map[string]string
+}
+
+// Realm returns the realm parameter.
+func (c Challenge) Realm() string { return c.Params["realm"] }
+
+// Service returns the service parameter.
+func (c Challenge) Service() string { return c.Params["service"] }
+
+// Scopes returns the scopes listed in the scope parameter.
+func (c Challenge) Scopes() ([]Scope, error) {
+	var scopes []Scope
+	for _, s := range strings.Fields(c.Params["scope"]) {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// ParseChallenges parses the values of one or more WWW-Authenticate headers.
+func ParseChallenges(values []string) ([]Challenge, error) {
+	var challenges []Challenge
+	for _, v := range values {
+		s := strings.TrimSpace(v)
+		for s != "" {
+			i := strings.IndexAny(s, " ,")
+			if i < 0 {
+				i = len(s)
+			}
+			c := Challenge{Scheme: strings.ToLower(s[:i]), Params: map[string]string{}}
+			if c.Scheme == "" {
+				return nil, fmt.Errorf("auth: malformed WWW-Authenticate header %q: missing scheme", v)
+			}
+			s = strings.TrimSpace(s[i:])
+			for s != "" && s[0] != ',' {
+				name, value, rest, err := parseParam(s)
+				if err != nil {
+					return nil, fmt.Errorf("auth: malformed WWW-Authenticate header %q: %w", v, err)
+				}
+				c.Params[name] = value
+				s = strings.TrimSpace(rest)
+				if !strings.HasPrefix(s, ",") {
+					break
+				}
+				// a comma either separates params or starts the next challenge
+				next := strings.TrimSpace(s[1:])
+				if j := strings.IndexAny(next, "= ,"); j < 0 || next[j] != '=' {
+					break
+				}
+				s = next
+			}
+			challenges = append(challenges, c)
+			s = strings.TrimSpace(strings.TrimPrefix(s, ","))
+		}
+	}
+	return challenges, nil
+}
+
+// parseParam reads a name=value auth-param from the start of s and returns
+// the remainder of s.
+func parseParam(s string) (name, value, rest string, err error) {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return "", "", "", fmt.Errorf("expected auth-param at %q", s)
+	}
+	name = strings.ToLower(strings.TrimSpace(s[:i]))
+	s = strings.TrimSpace(s[i+1:])
+	if !strings.HasPrefix(s, `"`) {
+		j := strings.IndexByte(s, ',')
+		if j < 0 {
+			j = len(s)
+		}
+		return name, strings.TrimSpace(s[:j]), s[j:], nil
+	}
+	var b strings.Builder
+	for j := 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			if j < len(s) {
+				b.WriteByte(s[j])
+			}
+		case '"':
+			return name, b.String(), s[j+1:], nil
+		default:
+			b.WriteByte(s[j])
+		}
+	}
+	return "", "", "", fmt.Errorf("unterminated quoted value for %q", name)
+}
+
+// Scope is a resource scope, such as "repository:myorg/myrepo:pull,push".
+type Scope struct {
+	Type    string
+	Name    string
+	Actions []string
+}
+
+// ParseScope parses a single resource scope.
+func ParseScope(s string) (Scope, error) {
+	first, last := strings.IndexByte(s, ':'), strings.LastIndexByte(s, ':')
+	if first <= 0 || last == first || last == len(s)-1 {
+		return Scope{}, fmt.Errorf("auth: malformed scope %q", s)
+	}
+	return Scope{Type: s[:first], Name: s[first+1 : last], Actions: strings.Split(s[last+1:], ",")}, nil
+}
+
+// String returns the scope in its textual form.
+func (s Scope) String() string {
+	return s.Type + ":" + s.Name + ":" + strings.Join(s.Actions, ",")
+}
+
+// Allows reports whether the scope grants action, directly or through "*".
+func (s Scope) Allows(action string) bool {
+	for _, a := range s.Actions {
+		if a == action || a == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckChallenge checks that c is a well formed bearer challenge asking for
+// a repository scope on name that grants every action.
+func CheckChallenge(c Challenge, name string, actions ...string) error {
+	if c.Scheme != "bearer" {
+		return fmt.Errorf("auth: expected a bearer challenge, got %q", c.Scheme)
+	}
+	u, err := url.Parse(c.Realm())
+	if err != nil || !u.IsAbs() {
+		return fmt.Errorf("auth: realm %q is not an absolute URL", c.Realm())
+	}
+	scopes, err := c.Scopes()
+	if err != nil {
+		return err
+	}
+	for _, s := range scopes {
+		if s.Type != "repository" || s.Name != name {
+			continue
+		}
+		for _, a := range actions {
+			if !s.Allows(a) {
+				return fmt.Errorf("auth: scope %q does not grant %q", s, a)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("auth: challenge scope %q does not cover repository %q", c.Params["scope"], name)
+}
+
+// CheckRejected checks that a response to a request with an invalid, expired
+// or insufficient token is an error with the code UNAUTHORIZED or DENIED.
+func CheckRejected(status int, body []byte) error {
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return fmt.Errorf("auth: expected status 401 or 403, got %d", status)
+	}
+	errResp := &v1.ErrorResponse{}
+	if err := json.Unmarshal(body, errResp); err != nil {
+		return fmt.Errorf("auth: decoding error response: %w", err)
+	}
+	if !errors.Is(errResp, v1.ErrorCodeUnauthorized) && !errors.Is(errResp, v1.ErrorCodeDenied) {
+		return fmt.Errorf("auth: expected error code %s or %s, got %v", v1.ErrorCodeUnauthorized, v1.ErrorCodeDenied, errResp.Codes())
+	}
+	return nil
+}
+
+// FetchToken requests a token for scope from the realm of c, authenticating
+// with username and password when set.
+func FetchToken(client Doer, c Challenge, scope, username, password string) (string, error) {
+	u, err := url.Parse(c.Realm())
+	if err != nil {
+		return "", fmt.Errorf("auth: invalid realm %q: %w", c.Realm(), err)
+	}
+	q := u.Query()
+	if c.Service() != "" {
+		q.Set("service", c.Service())
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("auth: token request to %s returned %d", u.Redacted(), resp.StatusCode)
+	}
+	tr := tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", fmt.Errorf("auth: decoding token response: %w", err)
+	}
+	if tr.Token == "" {
+		tr.Token = tr.AccessToken
+	}
+	if tr.Token == "" {
+		return "", fmt.Errorf("auth: token response from %s holds no token", u.Redacted())
+	}
+	return tr.Token, nil
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token,omitempty"`
+	ExpiresIn   int    `json:"expires_in,omitempty"`
+	IssuedAt    string `json:"issued_at,omitempty"`
+}
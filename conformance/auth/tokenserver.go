@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+var (
+	// ErrInvalidToken is returned by Verify for tokens that were not issued
+	// by the token server.
+	ErrInvalidToken = errors.New("auth: invalid token")
+
+	// ErrExpiredToken is returned by Verify for expired tokens.
+	ErrExpiredToken = errors.New("auth: token expired")
+)
+
+// TokenServer is a minimal stand-in for the token server of a registry. It
+// grants every requested scope to known users and signs its tokens with a
+// random key, so that only the TokenServer that issued a token accepts it.
+type TokenServer struct {
+	// Service is the name of the service tokens are issued for.
+	Service string
+
+	// Users maps user names to passwords. Anonymous requests are granted
+	// tokens when Users is empty.
+	Users map[string]string
+
+	// TTL is the lifetime of the issued tokens.
+	TTL time.Duration
+
+	key []byte
+	now func() time.Time
+}
+
+type claims struct {
+	Service string   `json:"aud"`
+	Scopes  []string `json:"access"`
+	Expiry  int64    `json:"exp"`
+}
+
+// NewTokenServer returns a TokenServer issuing tokens for service that are
+// valid for five minutes.
+func NewTokenServer(service string) *TokenServer {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return &TokenServer{Service: service, TTL: 5 * time.Minute, key: key, now: time.Now}
+}
+
+// Issue returns a token granting scopes that expires after ttl. A negative
+// ttl returns a token that is already expired.
+func (ts *TokenServer) Issue(ttl time.Duration, scopes ...Scope) string {
+	c := claims{Service: ts.Service, Expiry: ts.now().Add(ttl).Unix()}
+	for _, s := range scopes {
+		c.Scopes = append(c.Scopes, s.String())
+	}
+	payload, _ := json.Marshal(c)
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + ts.sign(p)
+}
+
+func (ts *TokenServer) sign(payload string) string {
+	mac := hmac.New(sha256.New, ts.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns the scopes granted by token.
+func (ts *TokenServer) Verify(token string) ([]Scope, error) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 || !hmac.Equal([]byte(token[i+1:]), []byte(ts.sign(token[:i]))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(token[:i])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	c := claims{}
+	if err := json.Unmarshal(payload, &c); err != nil || c.Service != ts.Service {
+		return nil, ErrInvalidToken
+	}
+	if ts.now().Unix() >= c.Expiry {
+		return nil, ErrExpiredToken
+	}
+	var scopes []Scope
+	for _, s := range c.Scopes {
+		scope, err := ParseScope(s)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// ServeHTTP implements the token endpoint: it grants the scopes requested
+// with the scope query parameters.
+func (ts *TokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("service") != ts.Service {
+		http.Error(w, "unknown service", http.StatusBadRequest)
+		return
+	}
+	if len(ts.Users) > 0 {
+		user, pass, ok := r.BasicAuth()
+		if !ok || ts.Users[user] != pass {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+	var scopes []Scope
+	for _, v := range q["scope"] {
+		for _, s := range strings.Fields(v) {
+			scope, err := ParseScope(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+	}
+	token := ts.Issue(ts.TTL, scopes...)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tokenResponse{
+		Token:     token,
+		ExpiresIn: int(ts.TTL.Seconds()),
+		IssuedAt:  ts.now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Protect returns a handler that requires a token issued by ts before
+// passing requests to next. Requests without a valid token are answered with
+// 401 UNAUTHORIZED and a bearer challenge pointing at realm; requests whose
+// token does not grant the scope of the operation with 403 DENIED.
+func (ts *TokenServer) Protect(realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required, ok := requiredScope(r)
+		challenge := fmt.Sprintf("Bearer realm=%q,service=%q", realm, ts.Service)
+		if ok {
+			challenge += fmt.Sprintf(",scope=%q", required.String())
+		}
+
+		token := ""
+		if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+			token = h[7:]
+		}
+		granted, err := ts.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", challenge)
+			writeError(w, http.StatusUnauthorized, v1.ErrorCodeUnauthorized, err.Error())
+			return
+		}
+		if ok && !grants(granted, required) {
+			writeError(w, http.StatusForbidden, v1.ErrorCodeDenied, fmt.Sprintf("token does not grant %s", required))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiredScope returns the repository scope needed for r. It returns false
+// for requests outside a repository, such as the base endpoint.
+func requiredScope(r *http.Request) (Scope, bool) {
+	e, ok := endpoint.Match(r.Method, r.URL)
+	if !ok {
+		return Scope{}, false
+	}
+	name := e.PathVars(r.URL.Path)["name"]
+	if name == "" {
+		return Scope{}, false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return Scope{Type: "repository", Name: name, Actions: []string{"pull"}}, true
+	case http.MethodDelete:
+		return Scope{Type: "repository", Name: name, Actions: []string{"delete"}}, true
+	default:
+		return Scope{Type: "repository", Name: name, Actions: []string{"pull", "push"}}, true
+	}
+}
+
+func grants(granted []Scope, required Scope) bool {
+	for _, g := range granted {
+		if g.Type != required.Type || g.Name != required.Name {
+			continue
+		}
+		ok := true
+		for _, a := range required.Actions {
+			ok = ok && g.Allows(a)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, code v1.ErrorCode, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v1.ErrorResponse{Errors: []v1.ErrorInfo{{
+		Code:    string(code),
+		Message: code.Description(),
+		Detail:  detail,
+	}}})
+}
@@ -0,0 +1,246 @@
+// Command conformance-diff compares two results reports of the conformance
+// suite, such as those of two releases of a registry, and lists the specs that
+// newly fail, pass or are skipped, and those that got slower.
+//
+// Usage:
+//
+//	conformance-diff [-threshold duration] old new
+//
+// The reports are results.json or junit.xml files, in any combination. The
+// exit status is 1 when a spec newly fails and 2 when a report cannot be read.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/reporters"
+)
+
+// suiteDescription is the text of the top container of the suite, prefixing
+// the names of the test cases of junit.xml.
+const suiteDescription = "OCI Distribution Conformance Tests"
+
+// resultsSchemaVersion is the newest version of the results.json format this
+// command reads.
+const resultsSchemaVersion = 1
+
+// spec is the outcome of a spec in a report.
+type spec struct {
+	// name identifies the spec across reports: its workflow, category and
+	// text separated by spaces, as in junit.xml.
+	name     string
+	state    string
+	duration time.Duration
+}
+
+// report holds the specs of a report, in the order they ran.
+type report struct {
+	specs  []spec
+	byName map[string]spec
+}
+
+// change is a spec whose outcome differs between two reports.
+type change struct {
+	name          string
+	before, after spec
+	known         bool // the spec is in the old report
+}
+
+// diff is the difference between two reports.
+type diff struct {
+	failing, passing, skipped, slower []change
+	removed                           []string
+}
+
+func main() {
+	threshold := flag.Duration("threshold", time.Second, "report specs passing in both reports and slower by more than this")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-threshold duration] old new\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	before, err := readReport(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	after, err := readReport(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	d := compare(before, after, *threshold)
+	d.print(os.Stdout)
+	if len(d.failing) > 0 {
+		os.Exit(1)
+	}
+}
+
+// readReport reads a results.json or junit.xml report, telling them apart by
+// their content.
+func readReport(path string) (*report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r *report
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		r, err = parseJUnit(data)
+	} else {
+		r, err = parseResults(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// parseResults parses a results.json report.
+func parseResults(data []byte) (*report, error) {
+	var results struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Specs         []struct {
+			Workflow string        `json:"workflow"`
+			Category string        `json:"category"`
+			Name     string        `json:"name"`
+			State    string        `json:"state"`
+			Duration time.Duration `json:"duration"`
+		} `json:"specs"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	if results.SchemaVersion < 1 || results.SchemaVersion > resultsSchemaVersion {
+		return nil, fmt.Errorf("unsupported schemaVersion %d", results.SchemaVersion)
+	}
+	r := newReport()
+	for _, s := range results.Specs {
+		category := strings.ReplaceAll(s.Category, " / ", " ")
+		r.add(strings.Join([]string{s.Workflow, category, s.Name}, " "), s.State, s.Duration)
+	}
+	return r, nil
+}
+
+// parseJUnit parses a junit.xml report, ignoring the test cases of the
+// reporters.
+func parseJUnit(data []byte) (*report, error) {
+	var suites reporters.JUnitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		return nil, err
+	}
+	r := newReport()
+	for _, suite := range suites.TestSuites {
+		for _, tc := range suite.TestCases {
+			name := strings.TrimPrefix(tc.Name, "[It] ")
+			if !strings.HasPrefix(name, suiteDescription+" ") {
+				continue
+			}
+			r.add(strings.TrimPrefix(name, suiteDescription+" "), tc.Status,
+				time.Duration(tc.Time*float64(time.Second)))
+		}
+	}
+	return r, nil
+}
+
+func newReport() *report {
+	return &report{byName: map[string]spec{}}
+}
+
+// add adds a spec to r. Specs with the same name, such as repeated setup
+// steps, are told apart by a "#n" suffix.
+func (r *report) add(name, state string, duration time.Duration) {
+	key := name
+	for n := 2; ; n++ {
+		if _, ok := r.byName[key]; !ok {
+			break
+		}
+		key = fmt.Sprintf("%s #%d", name, n)
+	}
+	s := spec{name: key, state: state, duration: duration}
+	r.specs = append(r.specs, s)
+	r.byName[key] = s
+}
+
+// compare returns the changes from before to after. Specs passing in both
+// reports are slower when their duration grew by more than threshold.
+func compare(before, after *report, threshold time.Duration) diff {
+	var d diff
+	for _, s := range after.specs {
+		o, known := before.byName[s.name]
+		c := change{name: s.name, before: o, after: s, known: known}
+		switch {
+		case isFailure(s.state) && (!known || !isFailure(o.state)):
+			d.failing = append(d.failing, c)
+		case s.state == "passed" && o.state != "passed":
+			d.passing = append(d.passing, c)
+		case s.state == "skipped" && known && o.state != "skipped":
+			d.skipped = append(d.skipped, c)
+		case s.state == "passed" && o.state == "passed" && s.duration-o.duration > threshold:
+			d.slower = append(d.slower, c)
+		}
+	}
+	for _, s := range before.specs {
+		if _, ok := after.byName[s.name]; !ok {
+			d.removed = append(d.removed, s.name)
+		}
+	}
+	sort.SliceStable(d.slower, func(i, j int) bool {
+		return d.slower[i].after.duration-d.slower[i].before.duration > d.slower[j].after.duration-d.slower[j].before.duration
+	})
+	return d
+}
+
+// isFailure reports whether state is a failure: anything but passed, skipped
+// and pending.
+func isFailure(state string) bool {
+	return state != "passed" && state != "skipped" && state != "pending"
+}
+
+func (d diff) print(w io.Writer) {
+	section := func(title string, changes []change, line func(c change) string) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s (%d):\n", title, len(changes))
+		for _, c := range changes {
+			fmt.Fprintf(w, "  %s\n", line(c))
+		}
+		fmt.Fprintln(w)
+	}
+	transition := func(c change) string {
+		if !c.known {
+			return fmt.Sprintf("%s (new spec)", c.name)
+		}
+		return fmt.Sprintf("%s (was %s)", c.name, c.before.state)
+	}
+	section("Newly failing", d.failing, transition)
+	section("Newly passing", d.passing, transition)
+	section("Newly skipped", d.skipped, transition)
+	section("Slower", d.slower, func(c change) string {
+		return fmt.Sprintf("%s (%v -> %v)", c.name, c.before.duration, c.after.duration)
+	})
+	if len(d.removed) > 0 {
+		fmt.Fprintf(w, "Removed (%d):\n", len(d.removed))
+		for _, name := range d.removed {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(d.failing)+len(d.passing)+len(d.skipped)+len(d.slower)+len(d.removed) == 0 {
+		fmt.Fprintln(w, "No changes.")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const beforeJSON = `{
+  "schemaVersion": 1,
+  "specs": [
+    {"workflow": "Pull", "category": "Setup", "name": "Populate registry with test blob", "state": "passed", "duration": 1000000},
+    {"workflow": "Pull", "category": "Setup", "name": "Populate registry with test blob", "state": "passed", "duration": 1000000},
+    {"workflow": "Pull", "category": "Pull blobs", "name": "GET blob", "state": "passed", "duration": 1000000},
+    {"workflow": "Push", "category": "Blob Upload Chunked", "name": "PATCH chunk", "state": "failed", "duration": 1000000},
+    {"workflow": "Content Discovery", "category": "Listing tags", "name": "GET tags", "state": "passed", "duration": 1000000},
+    {"workflow": "Content Management", "category": "Delete", "name": "DELETE tag", "state": "passed", "duration": 1000000}
+  ]
+}`
+
+const afterJUnit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="7">
+  <testsuite name="conformance tests" tests="7">
+    <testcase name="OCI Distribution Conformance Tests Pull Setup Populate registry with test blob" status="passed" time="0.001"></testcase>
+    <testcase name="OCI Distribution Conformance Tests Pull Setup Populate registry with test blob" status="failed" time="0.001"><failure message="boom"></failure></testcase>
+    <testcase name="OCI Distribution Conformance Tests Pull Pull blobs GET blob" status="passed" time="3"></testcase>
+    <testcase name="OCI Distribution Conformance Tests Push Blob Upload Chunked PATCH chunk" status="passed" time="0.001"></testcase>
+    <testcase name="OCI Distribution Conformance Tests Content Discovery Listing tags GET tags" status="skipped" time="0"><skipped message="skipped"></skipped></testcase>
+    <testcase name="OCI Distribution Conformance Tests Warnings Headers Warning header" status="failed" time="0.001"><failure message="boom"></failure></testcase>
+    <testcase name="html custom reporter" status="passed" time="0"></testcase>
+  </testsuite>
+</testsuites>`
+
+func TestCompare(t *testing.T) {
+	before, err := parseResults([]byte(beforeJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := parseJUnit([]byte(afterJUnit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := compare(before, after, time.Second)
+
+	names := func(changes []change) []string {
+		var l []string
+		for _, c := range changes {
+			l = append(l, c.name)
+		}
+		return l
+	}
+	for _, tt := range []struct {
+		what      string
+		got, want []string
+	}{
+		{"failing", names(d.failing), []string{"Pull Setup Populate registry with test blob #2", "Warnings Headers Warning header"}},
+		{"passing", names(d.passing), []string{"Push Blob Upload Chunked PATCH chunk"}},
+		{"skipped", names(d.skipped), []string{"Content Discovery Listing tags GET tags"}},
+		{"slower", names(d.slower), []string{"Pull Pull blobs GET blob"}},
+		{"removed", d.removed, []string{"Content Management Delete DELETE tag"}},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.what, tt.got, tt.want)
+		}
+	}
+
+	if d := compare(after, after, time.Second); len(d.failing)+len(d.passing)+len(d.skipped)+len(d.slower)+len(d.removed) != 0 {
+		t.Errorf("a report differs from itself: %+v", d)
+	}
+}
+
+func TestParseResultsVersion(t *testing.T) {
+	if _, err := parseResults([]byte(`{"schemaVersion": 2, "specs": []}`)); err == nil {
+		t.Error("a report of a newer schema version is accepted")
+	}
+}
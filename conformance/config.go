@@ -0,0 +1,323 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/distribution-spec/specs-go/reference"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envVarConfigFile = "OCI_CONFIG_FILE"
+
+	redactedValue = "*****"
+)
+
+// Config is the configuration of a conformance run. The conformance binary
+// reads it with LoadConfig from the file selected with -config or
+// OCI_CONFIG_FILE, and every field can be overridden with the environment
+// variable documented in the README.
+type Config struct {
+	// Handler serves the registry under test in process when set, in place
+	// of the registry at RootURL.
+	Handler http.Handler `json:"-" yaml:"-"`
+
+	RootURL                   string          `json:"rootURL" yaml:"rootURL"`
+	Namespace                 string          `json:"namespace" yaml:"namespace"`
+	CrossmountNamespace       string          `json:"crossmountNamespace,omitempty" yaml:"crossmountNamespace,omitempty"`
+	Username                  string          `json:"username,omitempty" yaml:"username,omitempty"`
+	Password                  string          `json:"password,omitempty" yaml:"password,omitempty"`
+	AuthScope                 string          `json:"authScope,omitempty" yaml:"authScope,omitempty"`
+	Debug                     bool            `json:"debug" yaml:"debug"`
+	DeleteManifestBeforeBlobs bool            `json:"deleteManifestBeforeBlobs" yaml:"deleteManifestBeforeBlobs"`
+	Workflows                 WorkflowsConfig `json:"workflows" yaml:"workflows"`
+	TLS                       TLSConfig       `json:"tls" yaml:"tls"`
+	Report                    ReportConfig    `json:"report" yaml:"report"`
+}
+
+// WorkflowsConfig selects the workflows to run and holds their options.
+type WorkflowsConfig struct {
+	Pull              PullConfig             `json:"pull" yaml:"pull"`
+	Push              PushConfig             `json:"push" yaml:"push"`
+	ContentDiscovery  ContentDiscoveryConfig `json:"contentDiscovery" yaml:"contentDiscovery"`
+	ContentManagement WorkflowConfig         `json:"contentManagement" yaml:"contentManagement"`
+	Authentication    AuthenticationConfig   `json:"authentication" yaml:"authentication"`
+	Warnings          WorkflowConfig         `json:"warnings" yaml:"warnings"`
+}
+
+// WorkflowConfig is the configuration of a workflow without options.
+type WorkflowConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// PullConfig is the configuration of the Pull workflow. When TagName,
+// ManifestDigest and BlobDigest are all set, the content already in the
+// registry is pulled and the setup pushing it is skipped.
+type PullConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	TagName        string `json:"tagName,omitempty" yaml:"tagName,omitempty"`
+	ManifestDigest string `json:"manifestDigest,omitempty" yaml:"manifestDigest,omitempty"`
+	BlobDigest     string `json:"blobDigest,omitempty" yaml:"blobDigest,omitempty"`
+}
+
+// PushConfig is the configuration of the Push workflow. AutomaticCrossmount
+// is nil when the automatic content discovery test of cross-mounting is not
+// run, and otherwise tells whether the registry is expected to support it.
+type PushConfig struct {
+	Enabled             bool  `json:"enabled" yaml:"enabled"`
+	SkipEmptyLayer      bool  `json:"skipEmptyLayer" yaml:"skipEmptyLayer"`
+	AutomaticCrossmount *bool `json:"automaticCrossmount,omitempty" yaml:"automaticCrossmount,omitempty"`
+}
+
+// ContentDiscoveryConfig is the configuration of the Content Discovery
+// workflow. When TagList is set, the tags already in the registry are listed
+// and the setup pushing them is skipped.
+type ContentDiscoveryConfig struct {
+	Enabled            bool     `json:"enabled" yaml:"enabled"`
+	TagList            []string `json:"tagList,omitempty" yaml:"tagList,omitempty"`
+	ReferrersTagSchema bool     `json:"referrersTagSchema" yaml:"referrersTagSchema"`
+}
+
+// AuthenticationConfig is the configuration of the Authentication workflow.
+type AuthenticationConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	ExpiredToken string `json:"expiredToken,omitempty" yaml:"expiredToken,omitempty"`
+}
+
+// TLSConfig is the TLS configuration of the client.
+type TLSConfig struct {
+	CAFile             string `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	ServerName         string `json:"serverName,omitempty" yaml:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+}
+
+// ReportConfig is the configuration of the reports. Dir "none" disables them.
+type ReportConfig struct {
+	Dir                  string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	HideSkippedWorkflows bool   `json:"hideSkippedWorkflows" yaml:"hideSkippedWorkflows"`
+}
+
+// envOverrides maps the environment variables to the fields they override.
+var envOverrides = []struct {
+	name string
+	set  func(c *Config, v string) error
+}{
+	{envVarRootURL, setString(func(c *Config) *string { return &c.RootURL })},
+	{envVarNamespace, setString(func(c *Config) *string { return &c.Namespace })},
+	{envVarCrossmountNamespace, setString(func(c *Config) *string { return &c.CrossmountNamespace })},
+	{envVarUsername, setString(func(c *Config) *string { return &c.Username })},
+	{envVarPassword, setString(func(c *Config) *string { return &c.Password })},
+	{envVarAuthScope, setString(func(c *Config) *string { return &c.AuthScope })},
+	{envVarDebug, setBool(func(c *Config) *bool { return &c.Debug })},
+	{envVarDeleteManifestBeforeBlobs, setBool(func(c *Config) *bool { return &c.DeleteManifestBeforeBlobs })},
+	{envVarPull, setBool(func(c *Config) *bool { return &c.Workflows.Pull.Enabled })},
+	{envVarTagName, setString(func(c *Config) *string { return &c.Workflows.Pull.TagName })},
+	{envVarManifestDigest, setString(func(c *Config) *string { return &c.Workflows.Pull.ManifestDigest })},
+	{envVarBlobDigest, setString(func(c *Config) *string { return &c.Workflows.Pull.BlobDigest })},
+	{envVarPush, setBool(func(c *Config) *bool { return &c.Workflows.Push.Enabled })},
+	{envVarPushEmptyLayer, setBool(func(c *Config) *bool { return &c.Workflows.Push.SkipEmptyLayer })},
+	{envVarAutomaticCrossmount, func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		c.Workflows.Push.AutomaticCrossmount = &b
+		return err
+	}},
+	{envVarContentDiscovery, setBool(func(c *Config) *bool { return &c.Workflows.ContentDiscovery.Enabled })},
+	{envVarTagList, func(c *Config, v string) error {
+		c.Workflows.ContentDiscovery.TagList = strings.Split(v, ",")
+		return nil
+	}},
+	{envVarReferrersTagSchema, setBool(func(c *Config) *bool { return &c.Workflows.ContentDiscovery.ReferrersTagSchema })},
+	{envVarContentManagement, setBool(func(c *Config) *bool { return &c.Workflows.ContentManagement.Enabled })},
+	{envVarAuthentication, setBool(func(c *Config) *bool { return &c.Workflows.Authentication.Enabled })},
+	{envVarAuthExpiredToken, setString(func(c *Config) *string { return &c.Workflows.Authentication.ExpiredToken })},
+	{envVarWarnings, setBool(func(c *Config) *bool { return &c.Workflows.Warnings.Enabled })},
+	{envVarTLSCAFile, setString(func(c *Config) *string { return &c.TLS.CAFile })},
+	{envVarTLSCertFile, setString(func(c *Config) *string { return &c.TLS.CertFile })},
+	{envVarTLSKeyFile, setString(func(c *Config) *string { return &c.TLS.KeyFile })},
+	{envVarTLSServerName, setString(func(c *Config) *string { return &c.TLS.ServerName })},
+	{envVarTLSInsecureSkipVerify, setBool(func(c *Config) *bool { return &c.TLS.InsecureSkipVerify })},
+	{envVarReportDir, setString(func(c *Config) *string { return &c.Report.Dir })},
+	{envVarHideSkippedWorkflows, setBool(func(c *Config) *bool { return &c.Report.HideSkippedWorkflows })},
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		*field(c) = b
+		return err
+	}
+}
+
+// defaultConfig returns the configuration used when neither a file nor the
+// environment set a field.
+func defaultConfig() *Config {
+	return &Config{DeleteManifestBeforeBlobs: true}
+}
+
+// LoadConfig returns the effective configuration: the defaults, overridden by
+// the configuration file at path, or else at OCI_CONFIG_FILE, overridden by
+// the environment. The configuration is validated by Run.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(envVarConfigFile)
+	}
+	c := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read configuration file: %v", err)
+		}
+		if err := decodeConfig(c, data, strings.EqualFold(filepath.Ext(path), ".json")); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %v", path, err)
+		}
+	}
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// decodeConfig decodes data into c, rejecting unknown fields and values of
+// the wrong type.
+func decodeConfig(c *Config, data []byte, isJSON bool) error {
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(c)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides the fields of c whose environment variable is set.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, o := range envOverrides {
+		v, ok := lookup(o.name)
+		if !ok || v == "" {
+			continue
+		}
+		if err := o.set(c, v); err != nil {
+			return fmt.Errorf("invalid %s=%q: %v", o.name, v, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the values of c, reporting every invalid field.
+func (c *Config) validate() error {
+	var errs []string
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.RootURL == "" {
+		invalid("rootURL", "is required (set %s)", envVarRootURL)
+	} else if u, err := url.Parse(c.RootURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("rootURL", "%q is not an http or https URL", c.RootURL)
+	}
+	if c.Namespace == "" {
+		invalid("namespace", "is required (set %s)", envVarNamespace)
+	} else if err := reference.ValidateName(c.Namespace); err != nil {
+		invalid("namespace", "%v", err)
+	}
+	if c.CrossmountNamespace != "" {
+		if err := reference.ValidateName(c.CrossmountNamespace); err != nil {
+			invalid("crossmountNamespace", "%v", err)
+		}
+	}
+
+	p := c.Workflows.Pull
+	if p.TagName != "" {
+		if err := reference.ValidateTag(p.TagName); err != nil {
+			invalid("workflows.pull.tagName", "%v", err)
+		}
+	}
+	if p.ManifestDigest != "" {
+		if err := reference.ValidateDigest(p.ManifestDigest); err != nil {
+			invalid("workflows.pull.manifestDigest", "%v", err)
+		}
+	}
+	if p.BlobDigest != "" {
+		if err := reference.ValidateDigest(p.BlobDigest); err != nil {
+			invalid("workflows.pull.blobDigest", "%v", err)
+		}
+	}
+	for i, tag := range c.Workflows.ContentDiscovery.TagList {
+		if err := reference.ValidateTag(tag); err != nil {
+			invalid(fmt.Sprintf("workflows.contentDiscovery.tagList[%d]", i), "%v", err)
+		}
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		invalid("tls", "certFile and keyFile must be set together")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// workflows returns the bitmask of the enabled workflows.
+func (c *Config) workflows() int {
+	w := c.Workflows
+	tests := 0
+	for bit, enabled := range map[int]bool{
+		pull:              w.Pull.Enabled,
+		push:              w.Push.Enabled,
+		contentDiscovery:  w.ContentDiscovery.Enabled,
+		contentManagement: w.ContentManagement.Enabled,
+		authentication:    w.Authentication.Enabled,
+		warnings:          w.Warnings.Enabled,
+	} {
+		if enabled {
+			tests |= bit
+		}
+	}
+	return tests
+}
+
+// redacted returns a copy of c without credentials, for the reports.
+func (c *Config) redacted() *Config {
+	r := *c
+	for _, s := range []*string{&r.Username, &r.Password, &r.Workflows.Authentication.ExpiredToken} {
+		if *s != "" {
+			*s = redactedValue
+		}
+	}
+	return &r
+}
+
+// String returns c in YAML, without credentials.
+func (c *Config) String() string {
+	b := new(bytes.Buffer)
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.redacted()); err != nil {
+		return err.Error()
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package conformance
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	const file = `
+rootURL: https://registry.example.org
+namespace: myorg/myrepo
+password: secret
+workflows:
+  pull:
+    enabled: true
+    tagName: v1
+  push:
+    automaticCrossmount: false
+  contentDiscovery:
+    tagList: [a, b]
+tls:
+  serverName: registry.internal
+`
+	c := defaultConfig()
+	if err := decodeConfig(c, []byte(file), false); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		envVarNamespace: "other",
+		envVarPush:      "true",
+		envVarTagList:   "x,y,z",
+		envVarPull:      "",
+	}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+	if err := c.applyEnv(lookup); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.validate(); err != nil {
+		t.Fatal(err)
+	}
+	w := c.Workflows
+	switch {
+	case c.Namespace != "other", !w.Pull.Enabled, !w.Push.Enabled, !c.DeleteManifestBeforeBlobs,
+		w.Push.AutomaticCrossmount == nil || *w.Push.AutomaticCrossmount,
+		strings.Join(w.ContentDiscovery.TagList, ",") != "x,y,z", c.TLS.ServerName != "registry.internal":
+		t.Errorf("unexpected effective configuration:\n%s", c)
+	}
+	if got := c.workflows(); got != pull|push {
+		t.Errorf("workflows() = %b, want %b", got, pull|push)
+	}
+	if s := c.String(); strings.Contains(s, "secret") || !strings.Contains(s, redactedValue) {
+		t.Errorf("String() does not redact the password:\n%s", s)
+	}
+
+	env = map[string]string{envVarDebug: "yes please"}
+	if err := c.applyEnv(lookup); err == nil || !strings.Contains(err.Error(), envVarDebug) {
+		t.Errorf("applyEnv() with a malformed boolean: got %v", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, tt := range []struct {
+		file   string
+		isJSON bool
+		want   string
+	}{
+		{file: "rootURL: http://localhost\nnamespace: a\nunknown: 1\n", want: "field unknown not found"},
+		{file: "rootURL: http://localhost\nnamespace: a\ndebug: maybe\n", want: "cannot unmarshal"},
+		{file: `{"rootURL": "http://localhost", "namespace": "a", "workflow": {}}`, isJSON: true, want: `unknown field "workflow"`},
+		{file: "namespace: a\n", want: "rootURL: is required"},
+		{file: "rootURL: localhost:5000\nnamespace: a\n", want: "is not an http or https URL"},
+		{file: "rootURL: http://localhost\nnamespace: A/b\n", want: "namespace:"},
+		{file: "rootURL: http://localhost\nnamespace: a\nworkflows: {pull: {blobDigest: sha256:abc}}\n", want: "workflows.pull.blobDigest:"},
+		{file: "rootURL: http://localhost\nnamespace: a\nworkflows: {contentDiscovery: {tagList: [ok, -bad]}}\n", want: "workflows.contentDiscovery.tagList[1]:"},
+		{file: "rootURL: http://localhost\nnamespace: a\ntls: {certFile: c.pem}\n", want: "certFile and keyFile must be set together"},
+	} {
+		c := defaultConfig()
+		err := decodeConfig(c, []byte(tt.file), tt.isJSON)
+		if err == nil {
+			err = c.validate()
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got error %v, want %q", tt.file, err, tt.want)
+		}
+	}
+}
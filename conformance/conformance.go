@@ -0,0 +1,189 @@
+package conformance
+
+import (
+	"errors"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/reporters"
+	"github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+)
+
+// Result is the outcome of a conformance run.
+type Result struct {
+	// Passed is true when no spec failed.
+	Passed bool `json:"passed"`
+
+	// Duration is the run time of the suite.
+	Duration time.Duration `json:"duration"`
+
+	// Specs holds the result of every spec, in the order they ran.
+	Specs []SpecResult `json:"specs"`
+}
+
+// SpecResult is the outcome of a single spec.
+type SpecResult struct {
+	// Workflow is the title of the workflow of the spec, such as "Pull".
+	Workflow string `json:"workflow"`
+
+	// Category is the context of the spec within the workflow, such as
+	// "Pull blobs".
+	Category string `json:"category"`
+
+	// Name is the text of the spec.
+	Name string `json:"name"`
+
+	// State is "passed", "failed", "skipped", "pending", "panicked",
+	// "interrupted" or "aborted".
+	State string `json:"state"`
+
+	// Failure is the failure or skip message of the spec.
+	Failure string `json:"failure,omitempty"`
+
+	// Duration is the run time of the spec.
+	Duration time.Duration `json:"duration"`
+
+	// Endpoints lists the IDs of the endpoints the spec sent requests to,
+	// such as "end-2", in the order of their first request.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Warnings holds the Warning header values the registry returned while
+	// the spec ran.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Exchanges holds the HTTP requests the spec sent and their responses.
+	Exchanges []Exchange `json:"exchanges,omitempty"`
+}
+
+// Failed returns the specs that did not pass and were not skipped.
+func (r Result) Failed() []SpecResult {
+	var failed []SpecResult
+	for _, s := range r.Specs {
+		if s.State != types.SpecStatePassed.String() && s.State != types.SpecStateSkipped.String() &&
+			s.State != types.SpecStatePending.String() {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// Count returns the number of specs in state.
+func (r Result) Count(state string) int {
+	n := 0
+	for _, s := range r.Specs {
+		if s.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	errAlreadyRun = errors.New("conformance: Run can only be called once per process")
+	didRun        bool
+	suiteReport   g.Report
+)
+
+// Run runs the conformance suite against the registry described by conf,
+// failing t when a spec fails, and returns the result of every spec. When conf.Handler is set, the registry
+// is served from it on a local test server and conf.RootURL is ignored.
+//
+// The suite relies on the global state of Ginkgo, so Run can be called only
+// once per process: run the suites of several configurations from separate
+// test binaries or processes.
+func Run(t *testing.T, conf Config) Result {
+	t.Helper()
+	if didRun {
+		t.Fatal(errAlreadyRun)
+	}
+	didRun = true
+
+	if conf.Handler != nil {
+		srv := httptest.NewServer(conf.Handler)
+		defer srv.Close()
+		conf.RootURL = srv.URL
+	}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := setup(&conf); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Describe(suiteDescription, func() {
+		g.AfterEach(reportWarnings)
+		g.AfterEach(reportExchanges)
+
+		test00Base()
+		test01Pull()
+		test02Push()
+		test03ContentDiscovery()
+		test04ContentManagement()
+		test05Authentication()
+		test06Warnings()
+	})
+
+	RegisterFailHandler(g.Fail)
+	suiteConfig, reporterConfig := g.GinkgoConfiguration()
+	hr := newHTMLReporter(reportHTMLFilename)
+	g.ReportAfterEach(hr.afterReport)
+	g.ReportAfterSuite("html custom reporter", func(r g.Report) {
+		if err := hr.endSuite(r); err != nil {
+			log.Printf("\nWARNING: cannot write HTML summary report: %v", err)
+		}
+	})
+	g.ReportAfterSuite("junit custom reporter", func(r g.Report) {
+		if reportJUnitFilename != "" {
+			_ = reporters.GenerateJUnitReportWithConfig(r, reportJUnitFilename, reporters.JunitReportConfig{
+				OmitLeafNodeType: true,
+			})
+		}
+	})
+	g.ReportAfterSuite("result", func(r g.Report) {
+		suiteReport = r
+	})
+	passed := g.RunSpecs(t, "conformance tests", suiteConfig, reporterConfig)
+	res := newResult(passed, suiteReport)
+	if reportJSONFilename != "" {
+		if err := writeResultsReport(reportJSONFilename, res, suiteReport); err != nil {
+			log.Printf("\nWARNING: cannot write JSON results report: %v", err)
+		}
+	}
+	if reportHARFilename != "" {
+		if err := writeHAR(reportHARFilename, res.Specs); err != nil {
+			log.Printf("\nWARNING: cannot write HAR file: %v", err)
+		}
+	}
+	return res
+}
+
+// newResult converts the report of the suite to a Result.
+func newResult(passed bool, r g.Report) Result {
+	res := Result{Passed: passed, Duration: r.RunTime}
+	for _, s := range r.SpecReports {
+		if s.LeafNodeType != types.NodeTypeIt {
+			continue
+		}
+		spec := SpecResult{
+			Name:     s.LeafNodeText,
+			State:    s.State.String(),
+			Failure:  s.Failure.Message,
+			Duration: s.RunTime,
+		}
+		// the hierarchy is the suite, the workflow and the category
+		if h := s.ContainerHierarchyTexts; len(h) > 1 {
+			spec.Workflow = h[1]
+			spec.Category = strings.Join(h[2:], " / ")
+		}
+		spec.Warnings, _ = specWarnings(s)
+		spec.Exchanges = specExchanges(s)
+		spec.Endpoints = specEndpoints(spec.Exchanges)
+		res.Specs = append(res.Specs, spec)
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+package conformance
+
+import (
+	"strings"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+type (
+	// coverageRow counts the specs that exercised an endpoint or received
+	// an error code.
+	coverageRow struct {
+		ID     string
+		Name   string
+		Specs  int
+		Passed int
+		Failed int
+	}
+
+	// coverage holds the endpoints and error codes of the specification
+	// exercised by a run.
+	coverage struct {
+		Endpoints           []coverageRow
+		ErrorCodes          []coverageRow
+		UncoveredEndpoints  []string
+		UncoveredErrorCodes []string
+	}
+)
+
+// newCoverage returns the coverage of the endpoints and error codes of the
+// specification by specs. An error code is covered by the specs that received
+// it in an error response.
+func newCoverage(specs []SpecResult) coverage {
+	var c coverage
+	for _, e := range endpoint.All() {
+		row := coverageRow{ID: string(e.ID), Name: strings.Join(e.Methods, ", ") + " " + e.PathTemplate}
+		if len(e.QueryParams) > 0 {
+			row.Name += "?" + strings.Join(e.QueryParams, "&")
+		}
+		for _, s := range specs {
+			if contains(s.Endpoints, row.ID) {
+				row.count(s)
+			}
+		}
+		c.Endpoints = append(c.Endpoints, row)
+		if row.Specs == 0 {
+			c.UncoveredEndpoints = append(c.UncoveredEndpoints, row.ID)
+		}
+	}
+	for _, code := range v1.ErrorCodes() {
+		row := coverageRow{ID: code.ID(), Name: string(code)}
+		for _, s := range specs {
+			if receivedErrorCode(s, row.Name) {
+				row.count(s)
+			}
+		}
+		c.ErrorCodes = append(c.ErrorCodes, row)
+		if row.Specs == 0 {
+			c.UncoveredErrorCodes = append(c.UncoveredErrorCodes, row.Name)
+		}
+	}
+	return c
+}
+
+func (r *coverageRow) count(s SpecResult) {
+	r.Specs++
+	switch s.State {
+	case types.SpecStatePassed.String():
+		r.Passed++
+	case types.SpecStateSkipped.String(), types.SpecStatePending.String():
+	default:
+		r.Failed++
+	}
+}
+
+// receivedErrorCode reports whether the registry returned code during s.
+func receivedErrorCode(s SpecResult, code string) bool {
+	for _, e := range s.Exchanges {
+		if contains(e.ErrorCodes, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
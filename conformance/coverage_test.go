@@ -0,0 +1,40 @@
+package conformance
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	specs := []SpecResult{
+		{State: "passed", Endpoints: []string{"end-2", "end-3"}},
+		{State: "failed", Endpoints: []string{"end-2"}, Exchanges: []Exchange{
+			{Endpoint: "end-2", Status: 404, ErrorCodes: []string{"BLOB_UNKNOWN"}},
+		}},
+		{State: "skipped"},
+	}
+	c := newCoverage(specs)
+
+	rows := map[string]coverageRow{}
+	for _, r := range append(c.Endpoints, c.ErrorCodes...) {
+		rows[r.ID] = r
+	}
+	for id, want := range map[string][3]int{
+		"end-2":  {2, 1, 1},
+		"end-3":  {1, 1, 0},
+		"end-4a": {0, 0, 0},
+		"code-1": {1, 0, 1},
+		"code-2": {0, 0, 0},
+	} {
+		r := rows[id]
+		if got := [3]int{r.Specs, r.Passed, r.Failed}; got != want {
+			t.Errorf("%s: got %v specs, passed and failed, want %v", id, got, want)
+		}
+	}
+	if len(c.Endpoints) != 16 || len(c.ErrorCodes) != 14 {
+		t.Errorf("got %d endpoints and %d error codes, want 16 and 14", len(c.Endpoints), len(c.ErrorCodes))
+	}
+	if len(c.UncoveredEndpoints) != 14 || !reflect.DeepEqual(c.UncoveredErrorCodes[len(c.UncoveredErrorCodes)-1], "TOOMANYREQUESTS") {
+		t.Errorf("got uncovered endpoints %v and error codes %v", c.UncoveredEndpoints, c.UncoveredErrorCodes)
+	}
+}
@@ -0,0 +1,291 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// harBodyLimit is the number of bytes of a textual body kept in the HAR file.
+// Longer bodies are truncated, and bodies that are not text are omitted.
+const harBodyLimit = 16 << 10
+
+type (
+	// capturedBody records the first harBodyLimit bytes of a body as it is
+	// read, and its size.
+	capturedBody struct {
+		contentType string
+		data        []byte
+		size        int64
+	}
+
+	// captureReader records what is read from a body in a capturedBody.
+	captureReader struct {
+		io.ReadCloser
+		body *capturedBody
+	}
+)
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.body.add(p[:n])
+	return n, err
+}
+
+func (b *capturedBody) add(p []byte) {
+	b.size += int64(len(p))
+	if room := harBodyLimit + 1 - len(b.data); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		b.data = append(b.data, p...)
+	}
+}
+
+// captureRequestBody returns a copy of req whose body is recorded in the
+// returned capturedBody as it is sent.
+func captureRequestBody(req *http.Request) (*http.Request, *capturedBody) {
+	body := &capturedBody{contentType: req.Header.Get("Content-Type")}
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, body
+	}
+	req = req.Clone(req.Context())
+	req.Body = &captureReader{ReadCloser: req.Body, body: body}
+	return req, body
+}
+
+// captureResponseBody records the body of resp in the returned capturedBody
+// as the client reads it.
+func captureResponseBody(resp *http.Response) *capturedBody {
+	body := &capturedBody{contentType: resp.Header.Get("Content-Type")}
+	resp.Body = &captureReader{ReadCloser: resp.Body, body: body}
+	return body
+}
+
+// text returns the body for the HAR file, with tokens redacted, and a comment
+// when the body is truncated or omitted.
+func (b *capturedBody) text() (text, comment string) {
+	if b == nil || b.size == 0 {
+		return "", ""
+	}
+	data := b.data
+	if len(data) > harBodyLimit {
+		data = data[:harBodyLimit]
+	}
+	if !isText(b.contentType) {
+		return "", fmt.Sprintf("body of %d bytes omitted", b.size)
+	}
+	if int64(len(data)) < b.size {
+		comment = fmt.Sprintf("body truncated to %d of %d bytes", len(data), b.size)
+	}
+	return harRedact(strings.ToValidUTF8(string(data), "")), comment
+}
+
+// isText reports whether a body of contentType can be shown as text.
+func isText(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(t, "text/") || strings.HasSuffix(t, "json") || strings.HasSuffix(t, "xml")
+}
+
+// HAR 1.2 types, see http://www.softwareishard.com/blog/har-12-spec/.
+type (
+	harFile struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Pages   []harPage  `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harPage struct {
+		StartedDateTime time.Time      `json:"startedDateTime"`
+		ID              string         `json:"id"`
+		Title           string         `json:"title"`
+		PageTimings     harPageTimings `json:"pageTimings"`
+		Comment         string         `json:"comment,omitempty"`
+	}
+
+	harPageTimings struct{}
+
+	harEntry struct {
+		PageRef         string      `json:"pageref"`
+		StartedDateTime time.Time   `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	harContent struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// writeHAR writes the exchanges of specs to filename as a HAR file, with a
+// page per spec.
+func writeHAR(filename string, specs []SpecResult) error {
+	data, err := json.MarshalIndent(newHAR(specs), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+func newHAR(specs []SpecResult) harFile {
+	l := harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "distribution-spec-conformance-tests", Version: Version},
+		Pages:   []harPage{},
+		Entries: []harEntry{},
+	}
+	for i, s := range specs {
+		if len(s.Exchanges) == 0 {
+			continue
+		}
+		page := harPage{
+			StartedDateTime: s.Exchanges[0].started,
+			ID:              fmt.Sprintf("spec-%d", i+1),
+			Title:           strings.Join([]string{s.Workflow, s.Category, s.Name}, " / "),
+			Comment:         s.State,
+		}
+		l.Pages = append(l.Pages, page)
+		for _, e := range s.Exchanges {
+			l.Entries = append(l.Entries, newHAREntry(page.ID, e))
+		}
+	}
+	return harFile{Log: l}
+}
+
+func newHAREntry(pageRef string, e Exchange) harEntry {
+	ms := float64(e.Duration) / float64(time.Millisecond)
+	entry := harEntry{
+		PageRef:         pageRef,
+		StartedDateTime: e.started,
+		Time:            ms,
+		Request: harRequest{
+			Method:      e.Method,
+			URL:         harRedact(e.URL),
+			HTTPVersion: e.proto,
+			Cookies:     []harNameValue{},
+			Headers:     harNameValues(e.RequestHeader),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			Status:      e.Status,
+			StatusText:  http.StatusText(e.Status),
+			HTTPVersion: e.proto,
+			Cookies:     []harNameValue{},
+			Headers:     harNameValues(e.ResponseHeader),
+			RedirectURL: harRedact(e.ResponseHeader.Get("Location")),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Send: 0, Wait: ms, Receive: 0},
+	}
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		entry.Request.QueryString = harNameValues(u.Query())
+	}
+	if b := e.requestBody; b != nil && b.size > 0 {
+		text, comment := b.text()
+		entry.Request.BodySize = b.size
+		entry.Request.PostData = &harPostData{MimeType: b.contentType, Text: text, Comment: comment}
+	}
+	if b := e.responseBody; b != nil {
+		text, comment := b.text()
+		entry.Response.BodySize = b.size
+		entry.Response.Content = harContent{Size: b.size, MimeType: b.contentType, Text: text, Comment: comment}
+	}
+	if e.Status == 0 {
+		entry.Comment = "the request failed"
+	}
+	return entry
+}
+
+// harRedact replaces the credentials and upload state tokens in s, such as
+// the "_state" query parameter of upload URLs.
+func harRedact(s string) string {
+	return redactRegexp.ReplaceAllString(s, redactReplace)
+}
+
+// harNameValues returns the values of m, such as headers, as HAR name and
+// value pairs in the order of their names, with tokens redacted.
+func harNameValues(m map[string][]string) []harNameValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	l := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range m[k] {
+			l = append(l, harNameValue{Name: k, Value: harRedact(v)})
+		}
+	}
+	return l
+}
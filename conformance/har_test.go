@@ -0,0 +1,82 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHAR(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "https://registry.example.org/v2/myorg/myrepo/blobs/uploads/1?_state=secret-token&digest=sha256:0",
+		bytes.NewReader(make([]byte, 3*harBodyLimit)))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	sent, requestBody := captureRequestBody(req)
+
+	resp := &http.Response{
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Location":     {"/v2/myorg/myrepo/blobs/uploads/1?_state=secret-token"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"token": "secret-token", "padding": "` + strings.Repeat("x", 2*harBodyLimit) + `"}`)),
+	}
+	responseBody := captureResponseBody(resp)
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	// the request body is recorded as the transport sends it
+	if _, err := io.ReadAll(sent.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	specs := []SpecResult{
+		{Workflow: titlePush, Category: "Blob Upload Monolithic", Name: "PUT", State: "failed", Exchanges: []Exchange{{
+			Method:         req.Method,
+			URL:            req.URL.String(),
+			Status:         http.StatusCreated,
+			Duration:       time.Millisecond,
+			RequestHeader:  redactHeader(req.Header),
+			ResponseHeader: resp.Header,
+			started:        time.Now(),
+			proto:          "HTTP/1.1",
+			requestBody:    requestBody,
+			responseBody:   responseBody,
+		}}},
+		{Workflow: titlePush, Category: "Blob Upload Monolithic", Name: "skipped", State: "skipped"},
+	}
+	data, err := json.Marshal(newHAR(specs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-token")) {
+		t.Errorf("credentials are not redacted: %s", data)
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatal(err)
+	}
+	if len(har.Log.Pages) != 1 || len(har.Log.Entries) != 1 {
+		t.Fatalf("got %d pages and %d entries, want 1 and 1", len(har.Log.Pages), len(har.Log.Entries))
+	}
+	e := har.Log.Entries[0]
+	if e.PageRef != har.Log.Pages[0].ID {
+		t.Errorf("entry of page %q, want %q", e.PageRef, har.Log.Pages[0].ID)
+	}
+	if p := e.Request.PostData; p == nil || p.Text != "" || p.Comment == "" || e.Request.BodySize != 3*harBodyLimit {
+		t.Errorf("binary request body is not omitted: %+v, size %d", p, e.Request.BodySize)
+	}
+	if c := e.Response.Content; len(c.Text) > harBodyLimit || !strings.Contains(c.Comment, "truncated") {
+		t.Errorf("long response body is not truncated: %d bytes, comment %q", len(c.Text), c.Comment)
+	}
+	if q := e.Request.QueryString; len(q) != 2 || q[0].Name != "_state" || q[1].Name != "digest" || q[1].Value != "sha256:0" {
+		t.Errorf("got query string %v", q)
+	}
+	if !strings.HasPrefix(e.Response.RedirectURL, "/v2/myorg/myrepo/blobs/uploads/1?_state=") {
+		t.Errorf("got redirect URL %q", e.Response.RedirectURL)
+	}
+}
@@ -0,0 +1,55 @@
+package conformance
+
+import (
+	"strings"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+// tagRepository is the repository, below the namespace, that the tag cases
+// are pushed to, keeping their tags out of the namespace.
+const tagRepository = "/tag-validation"
+
+// referenceCase is a repository name or tag that a registry is expected to
+// accept, or to reject with Code.
+type referenceCase struct {
+	Description string
+
+	// Value is the tag, or the path appended to the namespace for a name.
+	Value string
+
+	// Code is empty for a valid value.
+	Code v1.ErrorCode
+}
+
+// nameCases returns the repository names pushed below the namespace, with
+// edge cases of the <name> grammar.
+func nameCases() []referenceCase {
+	return []referenceCase{
+		{Description: "a deeply nested path", Value: strings.Repeat("/nested", 8)},
+		{Description: "period separators", Value: "/name.with.periods"},
+		{Description: "underscore separators", Value: "/name_with__underscores"},
+		{Description: "dash separators", Value: "/name-with--dashes"},
+		{Description: "mixed separators and digits", Value: "/v1.2-rc_0"},
+		{Description: "uppercase letters", Value: "/UpperCase", Code: v1.ErrorCodeNameInvalid},
+		{Description: "a leading separator", Value: "/-leading", Code: v1.ErrorCodeNameInvalid},
+		{Description: "a trailing separator", Value: "/trailing_", Code: v1.ErrorCodeNameInvalid},
+		{Description: "three underscores", Value: "/three___underscores", Code: v1.ErrorCodeNameInvalid},
+		{Description: "a parent directory component", Value: "/../escape", Code: v1.ErrorCodeNameInvalid},
+		{Description: "a current directory component", Value: "/./dot", Code: v1.ErrorCodeNameInvalid},
+	}
+}
+
+// tagCases returns the tags pushed to tagRepository, with edge cases of the
+// tag grammar.
+func tagCases() []referenceCase {
+	return []referenceCase{
+		{Description: "128 characters", Value: strings.Repeat("t", 128)},
+		{Description: "mixed case and separators", Value: "Tag_1.0-RC"},
+		{Description: "a leading underscore", Value: "_tag"},
+		{Description: "129 characters", Value: strings.Repeat("t", 129), Code: v1.ErrorCodeTagInvalid},
+		{Description: "a leading period", Value: ".tag", Code: v1.ErrorCodeTagInvalid},
+		{Description: "a leading dash", Value: "-tag", Code: v1.ErrorCodeTagInvalid},
+		{Description: "a plus sign", Value: "tag+plus", Code: v1.ErrorCodeTagInvalid},
+	}
+}
@@ -0,0 +1,29 @@
+package conformance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/opencontainers/distribution-spec/specs-go/reference"
+)
+
+func TestReferenceCases(t *testing.T) {
+	check := func(kind string, c referenceCase, err error) {
+		t.Helper()
+		switch {
+		case c.Code == "" && err != nil:
+			t.Errorf("%s with %s: expected valid, got %v", kind, c.Description, err)
+		case c.Code != "" && !errors.Is(err, c.Code):
+			t.Errorf("%s with %s: expected %s, got %v", kind, c.Description, c.Code, err)
+		}
+	}
+	for _, c := range nameCases() {
+		check("name", c, reference.ValidateName("myorg/myrepo"+c.Value))
+	}
+	for _, c := range tagCases() {
+		check("tag", c, reference.ValidateTag(c.Value))
+	}
+	if err := reference.ValidateName("myorg/myrepo" + tagRepository); err != nil {
+		t.Errorf("tag repository: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/distribution-spec/specs-go/reference"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+	godigest "github.com/opencontainers/go-digest"
+)
+
+// getBlob serves end-2, including range requests.
+func (r *Registry) getBlob(w http.ResponseWriter, req *http.Request, name, digest string) {
+	if err := reference.ValidateDigest(digest); err != nil {
+		writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+		return
+	}
+	repo := r.repo(name, false)
+	if repo == nil {
+		writeError(w, v1.ErrorCodeBlobUnknown, digest)
+		return
+	}
+	blob, ok := repo.blobs[digest]
+	if !ok {
+		writeError(w, v1.ErrorCodeBlobUnknown, digest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(blob))
+}
+
+// startUpload serves end-4a.
+func (r *Registry) startUpload(w http.ResponseWriter, name string) {
+	r.repo(name, true)
+	id := newID()
+	r.uploads[id] = &upload{name: name}
+	if r.ChunkMinLength > 0 {
+		w.Header().Set("OCI-Chunk-Min-Length", strconv.Itoa(r.ChunkMinLength))
+	}
+	writeUploadStatus(w, http.StatusAccepted, name, id, 0)
+}
+
+// putMonolithic serves end-4b.
+func (r *Registry) putMonolithic(w http.ResponseWriter, req *http.Request, name string) {
+	body, ok := readBlobBody(w, req)
+	if !ok {
+		return
+	}
+	r.putBlob(w, name, req.URL.Query().Get("digest"), body)
+}
+
+// patchUpload serves end-5. A chunk must start at the end of the data
+// received so far.
+func (r *Registry) patchUpload(w http.ResponseWriter, req *http.Request, name, id string) {
+	u, ok := r.uploads[id]
+	if !ok || u.name != name {
+		writeError(w, v1.ErrorCodeBlobUploadUnknown, id)
+		return
+	}
+	body, ok := readBlobBody(w, req)
+	if !ok {
+		return
+	}
+	if !checkChunk(w, req, name, id, u, body) {
+		return
+	}
+	u.data = append(u.data, body...)
+	writeUploadStatus(w, http.StatusAccepted, name, id, int64(len(u.data)))
+}
+
+// readBlobBody reads the body of a blob upload request. It writes an error
+// response and returns false when the body cannot be read, SIZE_INVALID when
+// it ends before its Content-Length.
+func readBlobBody(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(req.Body)
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		writeError(w, v1.ErrorCodeSizeInvalid, fmt.Sprintf("body ended before its Content-Length of %d bytes", req.ContentLength))
+		return nil, false
+	case err != nil:
+		writeError(w, v1.ErrorCodeBlobUploadInvalid, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// checkChunk checks that the Content-Range of a chunk, if any, starts at the
+// end of the data received so far and matches the length of body. It writes
+// an error response and returns false otherwise.
+func checkChunk(w http.ResponseWriter, req *http.Request, name, id string, u *upload, body []byte) bool {
+	cr := req.Header.Get("Content-Range")
+	if cr == "" {
+		return true
+	}
+	start, end, err := parseRange(cr)
+	if err != nil {
+		writeError(w, v1.ErrorCodeBlobUploadInvalid, err.Error())
+		return false
+	}
+	if start != int64(len(u.data)) || end-start+1 != int64(len(body)) {
+		writeUploadStatus(w, http.StatusRequestedRangeNotSatisfiable, name, id, int64(len(u.data)))
+		return false
+	}
+	return true
+}
+
+// closeUpload serves end-6, the body holding the last chunk, if any.
+func (r *Registry) closeUpload(w http.ResponseWriter, req *http.Request, name, id string) {
+	u, ok := r.uploads[id]
+	if !ok || u.name != name {
+		writeError(w, v1.ErrorCodeBlobUploadUnknown, id)
+		return
+	}
+	body, ok := readBlobBody(w, req)
+	if !ok {
+		return
+	}
+	if len(body) > 0 && !checkChunk(w, req, name, id, u, body) {
+		return
+	}
+	if r.putBlob(w, name, req.URL.Query().Get("digest"), append(u.data, body...)) {
+		delete(r.uploads, id)
+	}
+}
+
+// uploadStatus serves end-13.
+func (r *Registry) uploadStatus(w http.ResponseWriter, name, id string) {
+	u, ok := r.uploads[id]
+	if !ok || u.name != name {
+		writeError(w, v1.ErrorCodeBlobUploadUnknown, id)
+		return
+	}
+	writeUploadStatus(w, http.StatusNoContent, name, id, int64(len(u.data)))
+}
+
+// mount serves end-11. Without from, the blob is looked up in every
+// repository when AutomaticCrossmount is set. When the blob is not found, an
+// upload session is started instead.
+func (r *Registry) mount(w http.ResponseWriter, req *http.Request, name string) {
+	q := req.URL.Query()
+	digest, from := q.Get("mount"), q.Get("from")
+	var blob []byte
+	found := false
+	if from != "" {
+		if src := r.repo(from, false); src != nil {
+			blob, found = src.blobs[digest]
+		}
+	} else if r.AutomaticCrossmount {
+		for _, src := range r.repos {
+			if blob, found = src.blobs[digest]; found {
+				break
+			}
+		}
+	}
+	if !found {
+		r.startUpload(w, name)
+		return
+	}
+	r.repo(name, true).blobs[digest] = blob
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", name, digest))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// deleteBlob serves end-10.
+func (r *Registry) deleteBlob(w http.ResponseWriter, name, digest string) {
+	if !r.Delete {
+		writeError(w, v1.ErrorCodeUnsupported, "blob deletion is disabled")
+		return
+	}
+	if err := reference.ValidateDigest(digest); err != nil {
+		writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+		return
+	}
+	repo := r.repo(name, false)
+	if repo == nil {
+		writeError(w, v1.ErrorCodeBlobUnknown, digest)
+		return
+	}
+	if _, ok := repo.blobs[digest]; !ok {
+		writeError(w, v1.ErrorCodeBlobUnknown, digest)
+		return
+	}
+	delete(repo.blobs, digest)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// putBlob stores data as blob digest of repository name, and reports whether
+// it did.
+func (r *Registry) putBlob(w http.ResponseWriter, name, digest string, data []byte) bool {
+	if err := reference.ValidateDigest(digest); err != nil {
+		writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+		return false
+	}
+	d := godigest.Digest(digest)
+	if err := d.Validate(); err != nil {
+		writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+		return false
+	}
+	if actual := d.Algorithm().FromBytes(data); actual != d {
+		writeError(w, v1.ErrorCodeDigestInvalid, fmt.Sprintf("content has digest %s", actual))
+		return false
+	}
+	r.repo(name, true).blobs[digest] = data
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", name, digest))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+	return true
+}
+
+func writeUploadStatus(w http.ResponseWriter, status int, name, id string, size int64) {
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, id))
+	w.Header().Set("Docker-Upload-UUID", id)
+	end := size - 1
+	if end < 0 {
+		end = 0
+	}
+	w.Header().Set("Range", fmt.Sprintf("0-%d", end))
+	w.WriteHeader(status)
+}
+
+// parseRange parses the "<start>-<end>" value of a Content-Range header.
+func parseRange(s string) (start, end int64, err error) {
+	a, b, ok := strings.Cut(s, "-")
+	if ok {
+		start, err = strconv.ParseInt(a, 10, 64)
+	}
+	if ok && err == nil {
+		end, err = strconv.ParseInt(b, 10, 64)
+	}
+	if !ok || err != nil || start < 0 || end < start {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,238 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+	godigest "github.com/opencontainers/go-digest"
+)
+
+// Fault is a violation of the specification injected into the requests or
+// responses of a registry, used to check that the conformance suite detects
+// it.
+type Fault struct {
+	// Name identifies the fault, such as "missing-location".
+	Name string
+
+	// Description explains the violation.
+	Description string
+
+	// endpoints restricts the fault to requests addressed to these
+	// endpoints. The fault applies to every request when it is empty.
+	endpoints []endpoint.ID
+
+	// request, when set, rewrites the request before it is served.
+	request func(req *http.Request)
+
+	// response, when set, rewrites the response before it is sent.
+	response func(resp *response)
+}
+
+// Faults lists the faults known to Wrap.
+var Faults = []Fault{
+	{
+		Name:        "upload-start-status",
+		Description: "starting a blob upload answers 200 instead of 202",
+		endpoints:   []endpoint.ID{endpoint.End4a},
+		response:    replaceStatus(http.StatusAccepted, http.StatusOK),
+	},
+	{
+		Name:        "blob-put-status",
+		Description: "completing a blob upload answers 200 instead of 201",
+		endpoints:   []endpoint.ID{endpoint.End4b, endpoint.End6},
+		response:    replaceStatus(http.StatusCreated, http.StatusOK),
+	},
+	{
+		Name:        "manifest-put-status",
+		Description: "pushing a manifest answers 202 instead of 201",
+		endpoints:   []endpoint.ID{endpoint.End7},
+		response:    replaceStatus(http.StatusCreated, http.StatusAccepted),
+	},
+	{
+		Name:        "not-found-status",
+		Description: "missing content answers 200 instead of 404",
+		response:    replaceStatus(http.StatusNotFound, http.StatusOK),
+	},
+	{
+		Name:        "range-not-checked",
+		Description: "out of order chunks answer 202 instead of 416",
+		endpoints:   []endpoint.ID{endpoint.End5},
+		response:    replaceStatus(http.StatusRequestedRangeNotSatisfiable, http.StatusAccepted),
+	},
+	{
+		Name:        "wrong-content-range",
+		Description: "partial blob content answers a Content-Range for other bytes",
+		endpoints:   []endpoint.ID{endpoint.End2},
+		response: func(resp *response) {
+			if resp.status == http.StatusPartialContent {
+				resp.header.Set("Content-Range", "bytes 0-0/1")
+			}
+		},
+	},
+	{
+		Name:        "missing-subject-header",
+		Description: "pushing a manifest with a subject omits the OCI-Subject header while the referrers API is served",
+		endpoints:   []endpoint.ID{endpoint.End7},
+		response: func(resp *response) {
+			resp.header.Del("OCI-Subject")
+		},
+	},
+	{
+		Name:        "missing-location",
+		Description: "responses have no Location header",
+		response: func(resp *response) {
+			resp.header.Del("Location")
+		},
+	},
+	{
+		Name:        "wrong-content-digest",
+		Description: "the Docker-Content-Digest header holds the digest of other content",
+		response: func(resp *response) {
+			if resp.header.Get("Docker-Content-Digest") != "" {
+				resp.header.Set("Docker-Content-Digest", godigest.FromString("other content").String())
+			}
+		},
+	},
+	{
+		Name:        "unsorted-tags",
+		Description: "tags are listed in reverse lexical order",
+		endpoints:   []endpoint.ID{endpoint.End8a, endpoint.End8b},
+		response: func(resp *response) {
+			var list v1.TagList
+			if resp.status != http.StatusOK || json.Unmarshal(resp.body.Bytes(), &list) != nil {
+				return
+			}
+			for i, j := 0, len(list.Tags)-1; i < j; i, j = i+1, j-1 {
+				list.Tags[i], list.Tags[j] = list.Tags[j], list.Tags[i]
+			}
+			body, _ := json.Marshal(list)
+			resp.setBody(body)
+		},
+	},
+	{
+		Name:        "ignored-n",
+		Description: "listing tags ignores the n query parameter",
+		endpoints:   []endpoint.ID{endpoint.End8b},
+		request: func(req *http.Request) {
+			q := req.URL.Query()
+			q.Del("n")
+			req.URL.RawQuery = q.Encode()
+		},
+	},
+	{
+		Name:        "ignored-artifact-type",
+		Description: "listing referrers ignores the artifactType filter but claims to apply it",
+		endpoints:   []endpoint.ID{endpoint.End12b},
+		request: func(req *http.Request) {
+			q := req.URL.Query()
+			q.Del(v1.FilterArtifactType)
+			req.URL.RawQuery = q.Encode()
+		},
+		response: func(resp *response) {
+			resp.header.Set(v1.HeaderFiltersApplied, v1.FilterArtifactType)
+		},
+	},
+	{
+		Name:        "bad-error-json",
+		Description: "error responses have a plain text body instead of the error JSON",
+		response: func(resp *response) {
+			if resp.status >= http.StatusBadRequest && resp.body.Len() > 0 {
+				resp.header.Set("Content-Type", "text/plain")
+				resp.setBody([]byte(http.StatusText(resp.status) + "\n"))
+			}
+		},
+	},
+}
+
+// LookupFault returns the fault called name.
+func LookupFault(name string) (Fault, bool) {
+	for _, f := range Faults {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Fault{}, false
+}
+
+// Wrap returns a handler injecting f into the requests and responses of h.
+func (f Fault) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !f.applies(req) {
+			h.ServeHTTP(w, req)
+			return
+		}
+		if f.request != nil {
+			f.request(req)
+		}
+		resp := &response{header: w.Header()}
+		h.ServeHTTP(resp, req)
+		if resp.status == 0 {
+			resp.status = http.StatusOK
+		}
+		if f.response != nil {
+			f.response(resp)
+		}
+		w.WriteHeader(resp.status)
+		_, _ = w.Write(resp.body.Bytes())
+	})
+}
+
+func (f Fault) applies(req *http.Request) bool {
+	if len(f.endpoints) == 0 {
+		return true
+	}
+	e, ok := endpoint.Match(req.Method, req.URL)
+	if !ok {
+		return false
+	}
+	for _, id := range f.endpoints {
+		if id == e.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceStatus returns a response rewrite replacing status from with to.
+func replaceStatus(from, to int) func(resp *response) {
+	return func(resp *response) {
+		if resp.status == from {
+			resp.status = to
+		}
+	}
+}
+
+// response buffers a response so that a fault can rewrite it.
+type response struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *response) Header() http.Header {
+	return r.header
+}
+
+func (r *response) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *response) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// setBody replaces the body, keeping the Content-Length header consistent.
+func (r *response) setBody(b []byte) {
+	r.body.Reset()
+	r.body.Write(b)
+	if r.header.Get("Content-Length") != "" {
+		r.header.Set("Content-Length", strconv.Itoa(len(b)))
+	}
+}
@@ -0,0 +1,253 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/opencontainers/distribution-spec/specs-go/reference"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+	godigest "github.com/opencontainers/go-digest"
+)
+
+const mediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+
+// getManifest serves end-3.
+func (r *Registry) getManifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if isDigest(ref) {
+		if err := reference.ValidateDigest(ref); err != nil {
+			writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+			return
+		}
+	}
+	repo := r.repo(name, false)
+	if repo == nil {
+		writeError(w, v1.ErrorCodeManifestUnknown, ref)
+		return
+	}
+	digest := ref
+	if !isDigest(ref) {
+		digest = repo.tags[ref]
+	}
+	m, ok := repo.manifests[digest]
+	if !ok {
+		writeError(w, v1.ErrorCodeManifestUnknown, ref)
+		return
+	}
+	w.Header().Set("Content-Type", m.mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(m.content)))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(m.content)
+	}
+}
+
+// putManifest serves end-7. Image manifests must only reference blobs of the
+// repository and indexes manifests of the repository, while the subject of
+// either may not exist.
+func (r *Registry) putManifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if isDigest(ref) {
+		if err := reference.ValidateDigest(ref); err != nil {
+			writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+			return
+		}
+	} else if err := reference.ValidateTag(ref); err != nil {
+		writeError(w, v1.ErrorCodeTagInvalid, err.Error())
+		return
+	}
+	body := io.Reader(req.Body)
+	if r.MaxManifestSize > 0 {
+		body = io.LimitReader(body, r.MaxManifestSize+1)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, v1.ErrorCodeManifestInvalid, err.Error())
+		return
+	}
+	if r.MaxManifestSize > 0 && int64(len(content)) > r.MaxManifestSize {
+		writeErrorStatus(w, http.StatusRequestEntityTooLarge, v1.ErrorCodeManifestInvalid,
+			fmt.Sprintf("manifest is larger than %d bytes", r.MaxManifestSize))
+		return
+	}
+	digest := godigest.FromBytes(content).String()
+	if isDigest(ref) && ref != digest {
+		writeError(w, v1.ErrorCodeDigestInvalid, fmt.Sprintf("content has digest %s", digest))
+		return
+	}
+
+	m := &manifest{content: content, mediaType: req.Header.Get("Content-Type")}
+	if err := json.Unmarshal(content, &m.parsed); err != nil {
+		writeError(w, v1.ErrorCodeManifestInvalid, err.Error())
+		return
+	}
+	if m.mediaType == "" {
+		m.mediaType = m.parsed.MediaType
+	}
+	if m.parsed.MediaType != "" && m.parsed.MediaType != m.mediaType {
+		writeError(w, v1.ErrorCodeManifestInvalid,
+			fmt.Sprintf("mediaType %q does not match Content-Type %q", m.parsed.MediaType, m.mediaType))
+		return
+	}
+
+	repo := r.repo(name, true)
+	switch m.mediaType {
+	case v1.MediaTypeImageIndex:
+		for _, d := range m.parsed.Manifests {
+			if _, ok := repo.manifests[d.Digest]; !ok {
+				writeError(w, v1.ErrorCodeManifestBlobUnknown, d.Digest)
+				return
+			}
+		}
+	case mediaTypeImageManifest:
+		if m.parsed.Config == nil {
+			writeError(w, v1.ErrorCodeManifestInvalid, "config is required")
+			return
+		}
+		for _, d := range append([]v1.Descriptor{*m.parsed.Config}, m.parsed.Layers...) {
+			if _, ok := repo.blobs[d.Digest]; !ok {
+				writeError(w, v1.ErrorCodeManifestBlobUnknown, d.Digest)
+				return
+			}
+		}
+	default:
+		writeError(w, v1.ErrorCodeManifestInvalid, fmt.Sprintf("unsupported media type %q", m.mediaType))
+		return
+	}
+
+	r.seq++
+	m.seq = r.seq
+	repo.manifests[digest] = m
+	if !isDigest(ref) {
+		repo.tags[ref] = digest
+	}
+	if r.Referrers && m.parsed.Subject != nil {
+		w.Header().Set("OCI-Subject", m.parsed.Subject.Digest)
+	}
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", name, digest))
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// deleteManifest serves end-9. Deleting a tag leaves the manifest in place,
+// deleting a manifest removes its tags.
+func (r *Registry) deleteManifest(w http.ResponseWriter, name, ref string) {
+	if !r.Delete {
+		writeError(w, v1.ErrorCodeUnsupported, "manifest deletion is disabled")
+		return
+	}
+	repo := r.repo(name, false)
+	if repo == nil {
+		writeError(w, v1.ErrorCodeManifestUnknown, ref)
+		return
+	}
+	if !isDigest(ref) {
+		if _, ok := repo.tags[ref]; !ok {
+			writeError(w, v1.ErrorCodeManifestUnknown, ref)
+			return
+		}
+		delete(repo.tags, ref)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if _, ok := repo.manifests[ref]; !ok {
+		writeError(w, v1.ErrorCodeManifestUnknown, ref)
+		return
+	}
+	delete(repo.manifests, ref)
+	for tag, digest := range repo.tags {
+		if digest == ref {
+			delete(repo.tags, tag)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// listTags serves end-8a and end-8b, in lexical order.
+func (r *Registry) listTags(w http.ResponseWriter, req *http.Request, name string) {
+	repo := r.repo(name, false)
+	if repo == nil {
+		writeError(w, v1.ErrorCodeNameUnknown, name)
+		return
+	}
+	q := req.URL.Query()
+	last := q.Get("last")
+	tags := []string{}
+	for tag := range repo.tags {
+		if last == "" || tag > last {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	if q.Has("n") {
+		n, err := strconv.Atoi(q.Get("n"))
+		if err != nil || n < 0 {
+			writeError(w, v1.ErrorCodeUnsupported, fmt.Sprintf("invalid n %q", q.Get("n")))
+			return
+		}
+		if n < len(tags) {
+			tags = tags[:n]
+			if n > 0 {
+				next := url.Values{"n": {strconv.Itoa(n)}, "last": {tags[n-1]}}
+				w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?%s>; rel="next"`, name, next.Encode()))
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v1.TagList{Name: name, Tags: tags})
+}
+
+// listReferrers serves end-12a and end-12b, listing the manifests with the
+// given subject in the order they were pushed.
+func (r *Registry) listReferrers(w http.ResponseWriter, req *http.Request, name, digest string) {
+	if !r.Referrers {
+		http.NotFound(w, req)
+		return
+	}
+	if err := reference.ValidateDigest(digest); err != nil {
+		writeError(w, v1.ErrorCodeDigestInvalid, err.Error())
+		return
+	}
+	var referrers []*manifest
+	if repo := r.repo(name, false); repo != nil {
+		for _, m := range repo.manifests {
+			if m.parsed.Subject != nil && m.parsed.Subject.Digest == digest {
+				referrers = append(referrers, m)
+			}
+		}
+	}
+	sort.Slice(referrers, func(i, j int) bool { return referrers[i].seq < referrers[j].seq })
+
+	artifactType := req.URL.Query().Get(v1.FilterArtifactType)
+	resp := v1.NewReferrersResponse()
+	for _, m := range referrers {
+		d := m.descriptor()
+		if artifactType == "" || d.ArtifactType == artifactType {
+			resp.Manifests = append(resp.Manifests, d)
+		}
+	}
+	if artifactType != "" {
+		w.Header().Set(v1.HeaderFiltersApplied, v1.FilterArtifactType)
+	}
+	w.Header().Set("Content-Type", v1.MediaTypeImageIndex)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// descriptor returns the descriptor of m in a referrers response.
+func (m *manifest) descriptor() v1.Descriptor {
+	artifactType := m.parsed.ArtifactType
+	if artifactType == "" && m.parsed.Config != nil {
+		artifactType = m.parsed.Config.MediaType
+	}
+	return v1.Descriptor{
+		MediaType:    m.mediaType,
+		Digest:       godigest.FromBytes(m.content).String(),
+		Size:         int64(len(m.content)),
+		ArtifactType: artifactType,
+		Annotations:  m.parsed.Annotations,
+	}
+}
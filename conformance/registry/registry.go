@@ -0,0 +1,195 @@
+// Package registry implements an in-memory registry serving the API described
+// in /spec.md, used to check the conformance suite itself without a network.
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	"github.com/opencontainers/distribution-spec/specs-go/reference"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+	"github.com/opencontainers/distribution-spec/specs-go/warning"
+)
+
+// Registry is an http.Handler serving repositories kept in memory. The
+// optional features of the specification are enabled by New and can be
+// turned off before the first request.
+type Registry struct {
+	// Delete enables the deletion of manifests, tags and blobs. Delete
+	// requests are answered with 405 UNSUPPORTED when it is false.
+	Delete bool
+
+	// Referrers enables the referrers API and the OCI-Subject header. When
+	// it is false, clients fall back to the referrers tag schema.
+	Referrers bool
+
+	// AutomaticCrossmount mounts a blob from any repository holding it when
+	// a mount request has no from parameter.
+	AutomaticCrossmount bool
+
+	// MaxManifestSize is the size of the largest manifest accepted. Larger
+	// manifests are answered with 413. There is no limit when it is 0.
+	MaxManifestSize int64
+
+	// ChunkMinLength is advertised as the OCI-Chunk-Min-Length of upload
+	// sessions when it is positive.
+	ChunkMinLength int
+
+	// Warnings lists the text of the warnings added to every response.
+	Warnings []string
+
+	mu      sync.Mutex
+	repos   map[string]*repository
+	uploads map[string]*upload
+	seq     int
+}
+
+type repository struct {
+	blobs     map[string][]byte
+	manifests map[string]*manifest
+	tags      map[string]string
+}
+
+type manifest struct {
+	content   []byte
+	mediaType string
+	parsed    manifestFields
+	seq       int
+}
+
+// manifestFields holds the fields of image manifests and indexes the registry
+// needs. Other fields are ignored.
+type manifestFields struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType"`
+	Config       *v1.Descriptor    `json:"config"`
+	Layers       []v1.Descriptor   `json:"layers"`
+	Manifests    []v1.Descriptor   `json:"manifests"`
+	Subject      *v1.Descriptor    `json:"subject"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+type upload struct {
+	name string
+	data []byte
+}
+
+// New returns an empty Registry with every optional feature enabled.
+func New() *Registry {
+	return &Registry{
+		Delete:              true,
+		Referrers:           true,
+		AutomaticCrossmount: true,
+		MaxManifestSize:     4 << 20,
+		repos:               map[string]*repository{},
+		uploads:             map[string]*upload{},
+	}
+}
+
+// ServeHTTP dispatches requests to the endpoint they are addressed to.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for _, text := range r.Warnings {
+		w.Header().Add(warning.Header, warning.Warning{Code: warning.Code, Agent: warning.Agent, Text: text}.String())
+	}
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+
+	e, ok := endpoint.Match(req.Method, req.URL)
+	if !ok {
+		// a PUT closing an upload without the digest parameter
+		if req.Method == http.MethodPut && endpoint.MustLookup(endpoint.End6).PathVars(req.URL.Path) != nil {
+			writeError(w, v1.ErrorCodeDigestInvalid, "the digest query parameter is required")
+			return
+		}
+		http.NotFound(w, req)
+		return
+	}
+	if e.ID == endpoint.End1 {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	vars := e.PathVars(req.URL.Path)
+	if err := reference.ValidateName(vars["name"]); err != nil {
+		writeError(w, v1.ErrorCodeNameInvalid, err.Error())
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e.ID {
+	case endpoint.End2:
+		r.getBlob(w, req, vars["name"], vars["digest"])
+	case endpoint.End3:
+		r.getManifest(w, req, vars["name"], vars["reference"])
+	case endpoint.End4a:
+		r.startUpload(w, vars["name"])
+	case endpoint.End4b:
+		r.putMonolithic(w, req, vars["name"])
+	case endpoint.End5:
+		r.patchUpload(w, req, vars["name"], vars["reference"])
+	case endpoint.End6:
+		r.closeUpload(w, req, vars["name"], vars["reference"])
+	case endpoint.End7:
+		r.putManifest(w, req, vars["name"], vars["reference"])
+	case endpoint.End8a, endpoint.End8b:
+		r.listTags(w, req, vars["name"])
+	case endpoint.End9:
+		r.deleteManifest(w, vars["name"], vars["reference"])
+	case endpoint.End10:
+		r.deleteBlob(w, vars["name"], vars["digest"])
+	case endpoint.End11:
+		r.mount(w, req, vars["name"])
+	case endpoint.End12a, endpoint.End12b:
+		r.listReferrers(w, req, vars["name"], vars["digest"])
+	case endpoint.End13:
+		r.uploadStatus(w, vars["name"], vars["reference"])
+	}
+}
+
+// repo returns the repository called name, creating it when create is set.
+// It returns nil for a repository that does not exist.
+func (r *Registry) repo(name string, create bool) *repository {
+	repo, ok := r.repos[name]
+	if !ok && create {
+		repo = &repository{
+			blobs:     map[string][]byte{},
+			manifests: map[string]*manifest{},
+			tags:      map[string]string{},
+		}
+		r.repos[name] = repo
+	}
+	return repo
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func isDigest(ref string) bool {
+	return strings.Contains(ref, ":")
+}
+
+func writeError(w http.ResponseWriter, code v1.ErrorCode, detail string) {
+	writeErrorStatus(w, code.HTTPStatus(), code, detail)
+}
+
+// writeErrorStatus writes an error response with a status other than the one
+// of code.
+func writeErrorStatus(w http.ResponseWriter, status int, code v1.ErrorCode, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v1.ErrorResponse{Errors: []v1.ErrorInfo{{
+		Code:    string(code),
+		Message: code.Description(),
+		Detail:  detail,
+	}}})
+}
@@ -0,0 +1,206 @@
+package registry_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/distribution-spec/conformance"
+	"github.com/opencontainers/distribution-spec/conformance/auth"
+	"github.com/opencontainers/distribution-spec/conformance/registry"
+)
+
+const (
+	// envVariant selects the variant run by a child test process.
+	envVariant = "REGISTRY_TEST_VARIANT"
+
+	// envFault names the fault injected by a child test process.
+	envFault = "REGISTRY_TEST_FAULT"
+
+	// envResult names the file a child test process writes its result to.
+	envResult = "REGISTRY_TEST_RESULT"
+)
+
+var faultMatrix = flag.String("fault-matrix", "", "write the fault coverage matrix to this file")
+
+// variants configure the registry and the suite for each combination of
+// optional features the suite is checked against.
+var variants = map[string]func(t *testing.T, reg *registry.Registry, conf *conformance.Config){
+	"default": func(t *testing.T, reg *registry.Registry, conf *conformance.Config) {},
+	"minimal": func(t *testing.T, reg *registry.Registry, conf *conformance.Config) {
+		reg.Delete = false
+		reg.Referrers = false
+		reg.AutomaticCrossmount = false
+		// larger than the chunks the suite sends by default
+		reg.ChunkMinLength = 100
+		automaticCrossmount := false
+		conf.Workflows.Push.AutomaticCrossmount = &automaticCrossmount
+		// the Content Management workflow checks deletion
+		conf.Workflows.ContentManagement.Enabled = false
+	},
+	"auth": func(t *testing.T, reg *registry.Registry, conf *conformance.Config) {
+		ts := auth.NewTokenServer("registry.test")
+		ts.Users = map[string]string{"user": "pass"}
+		tokenSrv := httptest.NewServer(ts)
+		t.Cleanup(tokenSrv.Close)
+		conf.Handler = ts.Protect(tokenSrv.URL, reg)
+		conf.Username, conf.Password = "user", "pass"
+		conf.Workflows.Authentication.Enabled = true
+	},
+}
+
+// TestConformance runs the conformance suite against the in-memory registry.
+// The suite can run once per process, so the variants other than the
+// default one run in child processes.
+func TestConformance(t *testing.T) {
+	variant := os.Getenv(envVariant)
+	if variant == "" {
+		for name := range variants {
+			if name == "default" {
+				continue
+			}
+			name := name
+			t.Run(name, func(t *testing.T) {
+				cmd := exec.Command(os.Args[0], "-test.run=^TestConformance$", "-test.count=1")
+				cmd.Env = append(os.Environ(), envVariant+"="+name)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("variant %s: %v\n%s", name, err, out)
+				}
+			})
+		}
+		variant = "default"
+	}
+
+	reg := registry.New()
+	reg.Warnings = []string{"this registry is for testing only"}
+	automaticCrossmount := true
+	conf := conformance.Config{
+		Handler:                   reg,
+		Namespace:                 "conformance/test",
+		DeleteManifestBeforeBlobs: true,
+		Workflows: conformance.WorkflowsConfig{
+			Pull:              conformance.PullConfig{Enabled: true},
+			Push:              conformance.PushConfig{Enabled: true, AutomaticCrossmount: &automaticCrossmount},
+			ContentDiscovery:  conformance.ContentDiscoveryConfig{Enabled: true, ReferrersTagSchema: true},
+			ContentManagement: conformance.WorkflowConfig{Enabled: true},
+			Warnings:          conformance.WorkflowConfig{Enabled: true},
+		},
+		Report: conformance.ReportConfig{Dir: "none"},
+	}
+	variants[variant](t, reg, &conf)
+	if name := os.Getenv(envFault); name != "" {
+		fault, ok := registry.LookupFault(name)
+		if !ok {
+			t.Fatalf("unknown fault %q", name)
+		}
+		conf.Handler = fault.Wrap(conf.Handler)
+	}
+	result := conformance.Run(t, conf)
+	if path := os.Getenv(envResult); path != "" {
+		data, err := json.Marshal(result)
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(result.Specs) == 0 || result.Count("passed") == 0 {
+		t.Errorf("variant %s: no spec passed", variant)
+	}
+}
+
+// TestFaults runs the conformance suite against the in-memory registry with
+// each fault injected, and checks that at least one spec fails for each. The
+// workflows catching every fault are logged as a matrix.
+func TestFaults(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the suite runs once per fault")
+	}
+	var (
+		workflows []string
+		failures  = map[string]map[string]int{}
+	)
+	for _, fault := range registry.Faults {
+		fault := fault
+		t.Run(fault.Name, func(t *testing.T) {
+			path := t.TempDir() + "/result.json"
+			out, _ := runChild(envVariant+"=default", envFault+"="+fault.Name, envResult+"="+path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("no result: %v\n%s", err, out)
+			}
+			var result conformance.Result
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			failures[fault.Name] = map[string]int{}
+			for _, s := range result.Specs {
+				if !contains(workflows, s.Workflow) {
+					workflows = append(workflows, s.Workflow)
+				}
+			}
+			for _, s := range result.Failed() {
+				failures[fault.Name][s.Workflow]++
+			}
+			if len(failures[fault.Name]) == 0 {
+				t.Errorf("fault %s (%s) is not detected by any spec", fault.Name, fault.Description)
+			}
+		})
+	}
+
+	matrix := formatFaultMatrix(workflows, failures)
+	t.Logf("fault coverage (failed specs per workflow):\n%s", matrix)
+	if *faultMatrix != "" {
+		if err := os.WriteFile(*faultMatrix, []byte(matrix), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// formatFaultMatrix returns a Markdown table with a row per fault and a
+// column per workflow, holding the number of specs of the workflow failing
+// with the fault.
+func formatFaultMatrix(workflows []string, failures map[string]map[string]int) string {
+	var b strings.Builder
+	b.WriteString("| Fault | " + strings.Join(workflows, " | ") + " |\n")
+	b.WriteString("|---" + strings.Repeat("|---", len(workflows)) + "|\n")
+	for _, fault := range registry.Faults {
+		counts, ok := failures[fault.Name]
+		if !ok {
+			continue
+		}
+		b.WriteString("| " + fault.Name)
+		for _, w := range workflows {
+			cell := "-"
+			if n := counts[w]; n > 0 {
+				cell = fmt.Sprint(n)
+			}
+			b.WriteString(" | " + cell)
+		}
+		b.WriteString(" |\n")
+	}
+	return b.String()
+}
+
+// runChild runs TestConformance in a child process with the additional
+// environment variables env.
+func runChild(env ...string) ([]byte, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestConformance$", "-test.count=1")
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.CombinedOutput()
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
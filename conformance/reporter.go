@@ -10,7 +10,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 	"time"
 
 	"github.com/onsi/ginkgo/v2/types"
@@ -175,6 +174,22 @@ const (
         border: 1px solid #cccddd;
         border-radius: 6px;
       }
+      .warnings {
+        padding: 0 0 .5em 0;
+      }
+      .warnings li.malformed {
+        color: red;
+      }
+      tr.uncovered td {
+        color: grey;
+        font-style: italic;
+      }
+      tr.failing td {
+        background: #ffc8c8;
+      }
+      .uncovered-list {
+        color: red;
+      }
       h2 {
         margin-top: 45px;
       }
@@ -242,16 +257,44 @@ const (
         <td class="bullet-left">Test Version</td>
         <td>{{ .Version }}</td>
       </tr>
+      {{- if .TLSSetupFailure }}
+      <tr>
+        <td class="bullet-left">Setup Failure</td>
+        <td><pre class="fail-message">{{ .TLSSetupFailure }}</pre></td>
+      </tr>
+      {{- end }}
+      {{- if .APIVersion }}
+      <tr>
+        <td class="bullet-left">API Version</td>
+        <td>{{ .APIVersion }}</td>
+      </tr>
+      {{- end }}
+      {{- if .SubjectBehaviour }}
+      <tr>
+        <td class="bullet-left">Subject Processing</td>
+        <td>{{ .SubjectBehaviour }}</td>
+      </tr>
+      {{- end }}
       <tr>
         <td class="bullet-left">Configuration</td>
-        <td><div class="bullet-right">
-          {{ range $i, $s := .EnvironmentVariables }}
-            {{ $s }}<br />
-          {{ end }}
-        </div></td>
+        <td><pre class="bullet-right">{{ .Configuration }}</pre></td>
       </tr>
     </table>
 
+    <h2>Coverage</h2>
+    <div class="subcategory">
+      <h3>Endpoints</h3>
+      {{- if .Coverage.UncoveredEndpoints }}
+      <p class="uncovered-list">Not exercised by any spec: {{ range $i, $id := .Coverage.UncoveredEndpoints }}{{ if $i }}, {{ end }}{{ $id }}{{ end }}</p>
+      {{- end }}
+      {{template "coverage" .Coverage.Endpoints}}
+      <h3>Error Codes</h3>
+      {{- if .Coverage.UncoveredErrorCodes }}
+      <p class="uncovered-list">Not received by any spec: {{ range $i, $code := .Coverage.UncoveredErrorCodes }}{{ if $i }}, {{ end }}{{ $code }}{{ end }}</p>
+      {{- end }}
+      {{template "coverage" .Coverage.ErrorCodes}}
+    </div>
+
     <div>
       {{with .Suite}}
         {{$suite := .M}}
@@ -269,11 +312,12 @@ const (
                   {{$category := .M}}
                   {{range $k, $categoryKey := .Keys}}
                     {{$s := index $category $categoryKey}}
-                    {{if eq $s.State.String "failed"}}
+                    {{if or (eq $s.State.String "failed") (eq $s.State.String "aborted")}}
                       <div class="result red">
                         <div id="output-box-{{$s.ID}}-button" class="toggle" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">+</div>
                         <h4 style="display: inline;" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">{{$s.Title}}</h4>
                         <br>
+                        {{template "warnings" $s}}
                         <div>
                           <div id="output-box-{{$s.ID}}" style="display: none;">
                             <pre class="pre-box">{{$s.CombinedOutput}}</pre>
@@ -287,6 +331,7 @@ const (
                         <div id="output-box-{{$s.ID}}-button" class="toggle" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">+</div>
                         <h4 style="display: inline;" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">{{$s.Title}}</h4>
                         <br>
+                        {{template "warnings" $s}}
                         <div id="output-box-{{$s.ID}}" style="display: none;">
                           <pre class="pre-box">{{$s.CombinedOutput}}</pre>
                         </div>
@@ -296,6 +341,7 @@ const (
                         <div id="output-box-{{$s.ID}}-button" class="toggle" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">+</div>
                         <h4 style="display: inline;" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">{{$s.Title}}</h4>
                         <br>
+                        {{template "warnings" $s}}
                         <div id="output-box-{{$s.ID}}" style="display: none;">
                           <pre class="pre-box">{{$s.FailureMessage}}</pre>
                         </div>
@@ -305,6 +351,7 @@ const (
                         <div id="output-box-{{$s.ID}}-button" class="toggle" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">+</div>
                         <h4 style="display: inline;" onclick="javascript:toggleOutput('output-box-{{$s.ID}}')">{{$s.Title}}</h4>
                         <br>
+                        {{template "warnings" $s}}
                         <div id="output-box-{{$s.ID}}" style="display: none;">
                           <p>Unhandled state: {{ $s.State.String }}</p>
                           <pre class="pre-box">{{$s.CombinedOutput}}</pre>
@@ -323,6 +370,41 @@ const (
     </div>
   </body>
 </html>
+{{define "coverage"}}
+  <table>
+    <tr>
+      <th>ID</th>
+      <th>Name</th>
+      <th>Specs</th>
+      <th>Passed</th>
+      <th>Failed</th>
+    </tr>
+    {{- range .}}
+    <tr{{ if eq .Specs 0 }} class="uncovered"{{ else if gt .Failed 0 }} class="failing"{{ end }}>
+      <td>{{.ID}}</td>
+      <td><code>{{.Name}}</code></td>
+      <td>{{.Specs}}</td>
+      <td>{{.Passed}}</td>
+      <td>{{.Failed}}</td>
+    </tr>
+    {{- end}}
+  </table>
+{{end}}
+{{define "warnings"}}
+  {{- if or .Warnings .MalformedWarnings}}
+    <div class="warnings">
+      <b>Warning headers</b>
+      <ul>
+        {{- range .MalformedWarnings}}
+          <li class="malformed">{{.}}</li>
+        {{- end}}
+        {{- range .Warnings}}
+          <li>{{.}}</li>
+        {{- end}}
+      </ul>
+    </div>
+  {{- end}}
+{{end}}
 `
 )
 
@@ -352,11 +434,13 @@ type (
 
 	specSnapshot struct {
 		types.SpecReport
-		ID       int
-		Title    string
-		Category string
-		Suite    string
-		IsSetup  bool
+		ID                int
+		Title             string
+		Category          string
+		Suite             string
+		IsSetup           bool
+		Warnings          []string
+		MalformedWarnings []string
 	}
 
 	snapShotList []specSnapshot
@@ -372,30 +456,34 @@ type (
 	}
 
 	HTMLReporter struct {
-		htmlReportFilename   string
-		Suite                suite
-		SpecSummaryMap       summaryMap
-		EnvironmentVariables []string
-		Report               types.Report
-		debugLogger          *httpDebugWriter
-		debugIndex           int
-		enabledMap           map[string]bool
-		NumTotal             int
-		NumPassed            int
-		NumFailed            int
-		NumSkipped           int
-		PercentPassed        int
-		PercentFailed        int
-		PercentSkipped       int
-		startTime            time.Time
-		endTime              time.Time
-		StartTimeString      string
-		EndTimeString        string
-		RunTime              string
-		AllPassed            bool
-		AllFailed            bool
-		AllSkipped           bool
-		Version              string
+		htmlReportFilename string
+		Suite              suite
+		SpecSummaryMap     summaryMap
+		Configuration      string
+		Coverage           coverage
+		Report             types.Report
+		debugLogger        *httpDebugWriter
+		debugIndex         int
+		enabledMap         map[string]bool
+		NumTotal           int
+		NumPassed          int
+		NumFailed          int
+		NumSkipped         int
+		PercentPassed      int
+		PercentFailed      int
+		PercentSkipped     int
+		startTime          time.Time
+		endTime            time.Time
+		StartTimeString    string
+		EndTimeString      string
+		RunTime            string
+		AllPassed          bool
+		AllFailed          bool
+		AllSkipped         bool
+		Version            string
+		SubjectBehaviour   string
+		APIVersion         string
+		TLSSetupFailure    string
 	}
 )
 
@@ -468,55 +556,27 @@ func (l *httpDebugLogger) output(format string, v ...interface{}) {
 
 func newHTMLReporter(htmlReportFilename string) (h *HTMLReporter) {
 	enabledMap := map[string]bool{
+		titleBase:              true,
 		titlePull:              true,
 		titlePush:              true,
 		titleContentDiscovery:  true,
 		titleContentManagement: true,
+		titleAuthentication:    true,
+		titleWarnings:          true,
 	}
 
-	if os.Getenv(envVarHideSkippedWorkflows) == "1" {
+	if runConfig.Report.HideSkippedWorkflows {
 		enabledMap = map[string]bool{
+			titleBase:              true,
 			titlePull:              !userDisabled(pull),
 			titlePush:              !userDisabled(push),
 			titleContentDiscovery:  !userDisabled(contentDiscovery),
 			titleContentManagement: !userDisabled(contentManagement),
+			titleAuthentication:    !userDisabled(authentication),
+			titleWarnings:          !userDisabled(warnings),
 		}
 	}
 
-	varsToCheck := []string{
-		envVarRootURL,
-		envVarNamespace,
-		envVarUsername,
-		envVarPassword,
-		envVarDebug,
-		envVarPull,
-		envVarPush,
-		envVarContentDiscovery,
-		envVarContentManagement,
-		envVarPushEmptyLayer,
-		envVarBlobDigest,
-		envVarManifestDigest,
-		envVarTagName,
-		envVarTagList,
-		envVarHideSkippedWorkflows,
-		envVarAuthScope,
-		envVarCrossmountNamespace,
-	}
-	envVars := []string{}
-	for _, v := range varsToCheck {
-		var replacement string
-		if envVar := os.Getenv(v); envVar != "" {
-			replacement = envVar
-			if strings.Contains(v, "PASSWORD") || strings.Contains(v, "USERNAME") {
-				replacement = "*****"
-			}
-		} else {
-			continue
-		}
-		envVars = append(envVars,
-			fmt.Sprintf("%s=%s", v, replacement))
-	}
-
 	return &HTMLReporter{
 		htmlReportFilename: htmlReportFilename,
 		debugLogger:        httpWriter,
@@ -526,10 +586,10 @@ func newHTMLReporter(htmlReportFilename string) (h *HTMLReporter) {
 			M:    make(map[string]*workflow),
 			Keys: []string{},
 		},
-		EnvironmentVariables: envVars,
-		startTime:            time.Now(),
-		StartTimeString:      time.Now().Format("Jan 2 15:04:05.000 -0700 MST"),
-		Version:              Version,
+		Configuration:   runConfig.String(),
+		startTime:       time.Now(),
+		StartTimeString: time.Now().Format("Jan 2 15:04:05.000 -0700 MST"),
+		Version:         Version,
 	}
 }
 
@@ -559,13 +619,16 @@ func (reporter *HTMLReporter) afterReport(r types.SpecReport) {
 	z := suite.M[suiteName].M[categoryName]
 	z.Keys = append(z.Keys, titleText)
 
+	received, malformed := specWarnings(r)
 	suite.M[suiteName].M[categoryName].M[titleText] = specSnapshot{
-		SpecReport: r,
-		Suite:      suiteName,
-		Category:   categoryName,
-		Title:      titleText,
-		ID:         suite.Size,
-		IsSetup:    (categoryName == setupString),
+		SpecReport:        r,
+		Suite:             suiteName,
+		Category:          categoryName,
+		Title:             titleText,
+		ID:                suite.Size,
+		IsSetup:           (categoryName == setupString),
+		Warnings:          received,
+		MalformedWarnings: malformed,
 	}
 	suite.Size++
 }
@@ -579,10 +642,14 @@ func (reporter *HTMLReporter) endSuite(report types.Report) error {
 	reporter.endTime = time.Now()
 	reporter.EndTimeString = reporter.endTime.Format("Jan 2 15:04:05.000 -0700 MST")
 	reporter.RunTime = reporter.endTime.Sub(reporter.startTime).String()
+	reporter.SubjectBehaviour = subjectBehaviour
+	reporter.APIVersion = apiVersion
+	reporter.TLSSetupFailure = tlsSetupFailure
 	reporter.NumTotal = len(report.SpecReports)
 	reporter.NumPassed = report.SpecReports.CountWithState(types.SpecStatePassed)
 	reporter.NumSkipped = report.SpecReports.CountWithState(types.SpecStateSkipped)
 	reporter.NumFailed = report.SpecReports.CountWithState(types.SpecStateFailed)
+	reporter.Coverage = newCoverage(newResult(true, report).Specs)
 	reporter.PercentPassed = getPercent(reporter.NumPassed, reporter.NumTotal)
 	reporter.PercentSkipped = getPercent(reporter.NumSkipped, reporter.NumTotal)
 	reporter.PercentFailed = getPercent(reporter.NumFailed, reporter.NumTotal)
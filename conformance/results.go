@@ -0,0 +1,228 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+const (
+	// resultsSchemaVersion is the version of the JSON results report format,
+	// described by results.schema.json. It changes when a field is removed
+	// or changes meaning.
+	resultsSchemaVersion = 1
+
+	// reportEntryExchanges names the report entries holding the HTTP
+	// exchanges of a spec.
+	reportEntryExchanges = "HTTP exchanges"
+)
+
+// Exchange is an HTTP request sent to the registry and its response.
+type Exchange struct {
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+
+	// URL is the URL of the request.
+	URL string `json:"url"`
+
+	// Endpoint is the ID of the endpoint the request is addressed to, such as
+	// "end-2", or empty when it matches none.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Status is the status code of the response, or 0 when the request
+	// failed.
+	Status int `json:"status"`
+
+	// Duration is the time until the response headers arrived.
+	Duration time.Duration `json:"duration"`
+
+	// RequestHeader holds the request headers, with credentials redacted.
+	RequestHeader http.Header `json:"requestHeader,omitempty"`
+
+	// ResponseHeader holds the response headers, with credentials redacted.
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+
+	// ErrorCodes lists the codes of the error response body, such as
+	// "BLOB_UNKNOWN".
+	ErrorCodes []string `json:"errorCodes,omitempty"`
+
+	// started, proto and the bodies are kept for the HAR file only.
+	started      time.Time
+	proto        string
+	requestBody  *capturedBody
+	responseBody *capturedBody
+}
+
+// resultsReport is the JSON results report.
+type resultsReport struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Version       string        `json:"version"`
+	RegistryURL   string        `json:"registryURL"`
+	Seed          int64         `json:"seed"`
+	StartTime     time.Time     `json:"startTime"`
+	Duration      time.Duration `json:"duration"`
+	Passed        bool          `json:"passed"`
+	Config        *Config       `json:"config"`
+	Specs         []SpecResult  `json:"specs"`
+}
+
+// exchangeLog keeps the exchanges of the current spec.
+type exchangeLog struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// exchangeCollector collects the exchanges of the current spec.
+var exchangeCollector = &exchangeLog{}
+
+func (l *exchangeLog) add(e Exchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exchanges = append(l.exchanges, e)
+}
+
+// reset returns the exchanges collected so far and forgets them.
+func (l *exchangeLog) reset() []Exchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	exchanges := l.exchanges
+	l.exchanges = nil
+	return exchanges
+}
+
+// exchangeTransport records every request and response in
+// exchangeCollector.
+type exchangeTransport struct {
+	next http.RoundTripper
+}
+
+func (t *exchangeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := Exchange{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestHeader: redactHeader(req.Header),
+	}
+	if ep, ok := endpoint.Match(req.Method, req.URL); ok {
+		e.Endpoint = string(ep.ID)
+	}
+	req, e.requestBody = captureRequestBody(req)
+	e.started = time.Now()
+	resp, err := t.next.RoundTrip(req)
+	e.Duration = time.Since(e.started)
+	if err == nil {
+		e.Status = resp.StatusCode
+		e.proto = resp.Proto
+		e.ResponseHeader = redactHeader(resp.Header)
+		if resp.StatusCode >= http.StatusBadRequest {
+			e.ErrorCodes = errorCodesOf(resp)
+		}
+		e.responseBody = captureResponseBody(resp)
+	}
+	exchangeCollector.add(e)
+	return resp, err
+}
+
+// errorCodesOf returns the error codes of the body of resp, leaving the body
+// to be read again.
+func errorCodesOf(resp *http.Response) []string {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		return nil
+	}
+	var er v1.ErrorResponse
+	if json.Unmarshal(data, &er) != nil {
+		return nil
+	}
+	var codes []string
+	for _, e := range er.Errors {
+		codes = append(codes, e.Code)
+	}
+	return codes
+}
+
+// redactHeader returns a copy of h with the values of the headers carrying
+// credentials replaced.
+func redactHeader(h http.Header) http.Header {
+	r := h.Clone()
+	for _, k := range []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"} {
+		if _, ok := r[k]; ok {
+			r[k] = []string{redactedValue}
+		}
+	}
+	return r
+}
+
+// reportExchanges attaches the exchanges of the current spec to its report.
+func reportExchanges() {
+	if exchanges := exchangeCollector.reset(); len(exchanges) > 0 {
+		g.AddReportEntry(reportEntryExchanges, exchanges, g.ReportEntryVisibilityNever)
+	}
+}
+
+// specExchanges returns the exchanges attached to a spec report by
+// reportExchanges.
+func specExchanges(r types.SpecReport) []Exchange {
+	var exchanges []Exchange
+	for _, e := range r.ReportEntries {
+		if e.Name == reportEntryExchanges {
+			if l, ok := e.GetRawValue().([]Exchange); ok {
+				exchanges = append(exchanges, l...)
+			}
+		}
+	}
+	return exchanges
+}
+
+// specEndpoints returns the IDs of the endpoints of exchanges, in the order
+// they were first used.
+func specEndpoints(exchanges []Exchange) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, e := range exchanges {
+		if e.Endpoint != "" && !seen[e.Endpoint] {
+			seen[e.Endpoint] = true
+			ids = append(ids, e.Endpoint)
+		}
+	}
+	return ids
+}
+
+// writeResultsReport writes the JSON results report of res to filename.
+func writeResultsReport(filename string, res Result, r g.Report) error {
+	data, err := json.MarshalIndent(newResultsReport(res, r), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+func newResultsReport(res Result, r g.Report) resultsReport {
+	specs := res.Specs
+	if specs == nil {
+		specs = []SpecResult{}
+	}
+	return resultsReport{
+		SchemaVersion: resultsSchemaVersion,
+		Version:       Version,
+		RegistryURL:   runConfig.RootURL,
+		Seed:          seed,
+		StartTime:     r.StartTime,
+		Duration:      res.Duration,
+		Passed:        res.Passed,
+		Config:        runConfig.redacted(),
+		Specs:         specs,
+	}
+}
@@ -0,0 +1,102 @@
+package conformance
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+)
+
+func TestResultsReportSchema(t *testing.T) {
+	data, err := os.ReadFile("results.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	runConfig = defaultConfig()
+	runConfig.RootURL = "https://registry.example.org"
+	runConfig.Namespace = "myorg/myrepo"
+	runConfig.Password = "secret"
+	res := Result{
+		Passed:   false,
+		Duration: time.Second,
+		Specs: []SpecResult{{
+			Workflow:  titlePull,
+			Category:  "Pull blobs",
+			Name:      "GET nonexistent blob should result in 404 response",
+			State:     "failed",
+			Failure:   "Expected 404",
+			Duration:  time.Millisecond,
+			Endpoints: []string{"end-2"},
+			Warnings:  []string{`299 - "deprecated"`},
+			Exchanges: []Exchange{{
+				Method:         http.MethodGet,
+				URL:            "https://registry.example.org/v2/myorg/myrepo/blobs/sha256:0",
+				Endpoint:       "end-2",
+				Status:         http.StatusOK,
+				Duration:       time.Millisecond,
+				RequestHeader:  redactHeader(http.Header{"Authorization": {"Bearer abc"}}),
+				ResponseHeader: http.Header{"Content-Length": {"0"}},
+				ErrorCodes:     []string{"BLOB_UNKNOWN"},
+			}},
+		}},
+	}
+	data, err = json.Marshal(newResultsReport(res, g.Report{StartTime: time.Now()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "abc") {
+		t.Errorf("credentials are not redacted: %s", data)
+	}
+	var report interface{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	checkSchema(t, schema, schema, report, "")
+}
+
+// checkSchema checks that value has the required properties of node and no
+// properties missing from it, following references into the definitions of
+// root.
+func checkSchema(t *testing.T, root, node map[string]interface{}, value interface{}, path string) {
+	t.Helper()
+	if ref, ok := node["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		node = root["$defs"].(map[string]interface{})[name].(map[string]interface{})
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, ok := node["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		required, _ := node["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := v[r.(string)]; !ok {
+				t.Errorf("%s: missing required property %q", path, r)
+			}
+		}
+		for k, e := range v {
+			p, ok := props[k].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s: property %q is not in the schema", path, k)
+				continue
+			}
+			checkSchema(t, root, p, e, path+"/"+k)
+		}
+	case []interface{}:
+		if items, ok := node["items"].(map[string]interface{}); ok {
+			for _, e := range v {
+				checkSchema(t, root, items, e, path+"/*")
+			}
+		}
+	}
+}
@@ -6,18 +6,24 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	mathrand "math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bloodorangeio/reggie"
 	"github.com/google/uuid"
 	g "github.com/onsi/ginkgo/v2"
 	"github.com/onsi/ginkgo/v2/formatter"
+	"github.com/opencontainers/distribution-spec/specs-go/referrers"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
 	godigest "github.com/opencontainers/go-digest"
 )
 
@@ -39,6 +45,8 @@ const (
 	push
 	contentDiscovery
 	contentManagement
+	authentication
+	warnings
 	numWorkflows
 
 	BLOB_UNKNOWN = iota
@@ -56,6 +64,7 @@ const (
 	UNAUTHORIZED
 	DENIED
 	UNSUPPORTED
+	TOOMANYREQUESTS
 
 	envVarRootURL                   = "OCI_ROOT_URL"
 	envVarNamespace                 = "OCI_NAMESPACE"
@@ -66,6 +75,8 @@ const (
 	envVarPush                      = "OCI_TEST_PUSH"
 	envVarContentDiscovery          = "OCI_TEST_CONTENT_DISCOVERY"
 	envVarContentManagement         = "OCI_TEST_CONTENT_MANAGEMENT"
+	envVarAuthentication            = "OCI_TEST_AUTH"
+	envVarWarnings                  = "OCI_TEST_WARNINGS"
 	envVarPushEmptyLayer            = "OCI_SKIP_EMPTY_LAYER_PUSH_TEST"
 	envVarBlobDigest                = "OCI_BLOB_DIGEST"
 	envVarManifestDigest            = "OCI_MANIFEST_DIGEST"
@@ -73,18 +84,54 @@ const (
 	envVarTagList                   = "OCI_TAG_LIST"
 	envVarHideSkippedWorkflows      = "OCI_HIDE_SKIPPED_WORKFLOWS"
 	envVarAuthScope                 = "OCI_AUTH_SCOPE"
+	envVarAuthExpiredToken          = "OCI_AUTH_EXPIRED_TOKEN"
 	envVarDeleteManifestBeforeBlobs = "OCI_DELETE_MANIFEST_BEFORE_BLOBS"
 	envVarCrossmountNamespace       = "OCI_CROSSMOUNT_NAMESPACE"
 	envVarAutomaticCrossmount       = "OCI_AUTOMATIC_CROSSMOUNT"
+	envVarReferrersTagSchema        = "OCI_REFERRERS_TAG_SCHEMA"
 	envVarReportDir                 = "OCI_REPORT_DIR"
+	envVarTLSCAFile                 = "OCI_TLS_CA_FILE"
+	envVarTLSCertFile               = "OCI_TLS_CERT_FILE"
+	envVarTLSKeyFile                = "OCI_TLS_KEY_FILE"
+	envVarTLSServerName             = "OCI_TLS_SERVER_NAME"
+	envVarTLSInsecureSkipVerify     = "OCI_TLS_INSECURE_SKIP_VERIFY"
+
+	// debugBodyLimit is the size of the largest body written to the debug
+	// log, which keeps large manifests and blobs out of the HTML report.
+	debugBodyLimit = 64 << 10
+
+	// userAgent is the User-Agent of every request of the suite.
+	userAgent = "distribution-spec-conformance-tests"
+	// rawConnTimeout bounds a request sent without the HTTP client.
+	rawConnTimeout = 30 * time.Second
 
 	emptyLayerTestTag = "emptylayer"
 	testTagName       = "tagtest0"
 
+	// testChunkSize is the size of the chunks of the blob uploaded in many
+	// chunks, unless the registry advertises a larger minimum.
+	testChunkSize = 64
+	// testChunkCount is the number of full chunks of that blob.
+	testChunkCount = 8
+
+	// testLargeManifestSize is the size of a manifest every registry must
+	// accept. The specification asks for at least 4 megabytes, taken as
+	// 4 MiB so that the larger reading is covered.
+	testLargeManifestSize = 4 << 20
+	// testOversizedManifestSize is the size of a manifest registries are
+	// expected to reject as too large.
+	testOversizedManifestSize = 16 << 20
+
+	titleBase              = "Base"
 	titlePull              = "Pull"
 	titlePush              = "Push"
 	titleContentDiscovery  = "Content Discovery"
 	titleContentManagement = "Content Management"
+	titleAuthentication    = "Authentication"
+	titleWarnings          = "Warnings"
+
+	subjectBehaviourHeader   = "referrers API (OCI-Subject header returned)"
+	subjectBehaviourFallback = "referrers tag schema (no OCI-Subject header, fallback tag maintained by the client)"
 
 	//	layerBase64String is a base64 encoding of a simple tarball, obtained like this:
 	//		$ echo 'you bothered to find out what was in here. Congratulations!' > test.txt
@@ -93,9 +140,6 @@ const (
 	layerBase64String = "H4sIAAAAAAAAA+3OQQrCMBCF4a49xXgBSUnaHMCTRBptQRNpp6i3t0UEV7oqIv7fYgbmzeJpHHSjVy0" +
 		"WZCa1c/MufWVe94N3RWlrZ72x3k/30nhbFWKWLPU0Dhp6keJ8im//PuU/6pZH2WVtYx8b0Sz7LjWSR5VLG6YRBumSzOlGtjkd+qD" +
 		"jMWiX07Befbs7AAAAAAAAAAAAAAAAAPyzO34MnqoAKAAA"
-
-	// filter types
-	artifactTypeFilter = "artifactType"
 )
 
 var (
@@ -104,6 +148,8 @@ var (
 		envVarPush:              push,
 		envVarContentDiscovery:  contentDiscovery,
 		envVarContentManagement: contentManagement,
+		envVarAuthentication:    authentication,
+		envVarWarnings:          warnings,
 	}
 
 	testBlobA                          []byte
@@ -120,6 +166,7 @@ var (
 	testRefBlobBDigest                 string
 	testBlobB                          []byte
 	testBlobBDigest                    string
+	testChunkedBlob                    chunkedBlob
 	testBlobBChunk1                    []byte
 	testBlobBChunk1Length              string
 	testBlobBChunk2                    []byte
@@ -131,6 +178,7 @@ var (
 	client                             *reggie.Client
 	crossmountNamespace                string
 	dummyDigest                        string
+	fallbackSubjectDigest              string
 	errorCodes                         []string
 	invalidManifestContent             []byte
 	layerBlobData                      []byte
@@ -138,9 +186,12 @@ var (
 	layerBlobContentLength             string
 	emptyLayerManifestContent          []byte
 	emptyLayerManifestDigest           string
+	unknownBlobManifestContent         []byte
+	mismatchedTypeManifestContent      []byte
+	largeManifestRefs                  []string
 	nonexistentManifest                string
 	emptyJSONBlob                      []byte
-	emptyJSONDescriptor                descriptor
+	emptyJSONDescriptor                v1.Descriptor
 	refsManifestAConfigArtifactContent []byte
 	refsManifestAConfigArtifactDigest  string
 	refsManifestALayerArtifactContent  []byte
@@ -155,6 +206,8 @@ var (
 	refsIndexArtifactDigest            string
 	reportJUnitFilename                string
 	reportHTMLFilename                 string
+	reportJSONFilename                 string
+	reportHARFilename                  string
 	httpWriter                         *httpDebugWriter
 	testsToRun                         int
 	suiteDescription                   string
@@ -165,49 +218,53 @@ var (
 	deleteManifestBeforeBlobs          bool
 	runAutomaticCrossmountTest         bool
 	automaticCrossmountEnabled         bool
+	runReferrersTagSchemaTest          bool
+	subjectBehaviour                   string
+	apiVersion                         string
+	tlsSetupFailure                    string
+	runConfig                          *Config
 	configs                            []TestBlob
 	manifests                          []TestBlob
 	seed                               int64
 	Version                            = "unknown"
 )
 
-func init() {
+// setup prepares the suite for the configuration conf.
+func setup(conf *Config) error {
 	var err error
 
+	runConfig = conf
 	seed = g.GinkgoRandomSeed()
-	hostname := os.Getenv(envVarRootURL)
-	namespace := os.Getenv(envVarNamespace)
-	username := os.Getenv(envVarUsername)
-	password := os.Getenv(envVarPassword)
-	authScope := os.Getenv(envVarAuthScope)
-	crossmountNamespace = os.Getenv(envVarCrossmountNamespace)
+	crossmountNamespace = conf.CrossmountNamespace
 	if len(crossmountNamespace) == 0 {
 		crossmountNamespace = fmt.Sprintf("conformance-%s", uuid.New())
 	}
 
-	debug, _ := strconv.ParseBool(os.Getenv(envVarDebug))
+	testsToRun = conf.workflows()
 
-	for envVar, enableTest := range testMap {
-		if varIsTrue, _ := strconv.ParseBool(os.Getenv(envVar)); varIsTrue {
-			testsToRun |= enableTest
-		}
+	tlsConfig, err := newTLSConfig(conf.TLS)
+	if err != nil {
+		return err
 	}
 
-	httpWriter = newHTTPDebugWriter(debug)
+	httpWriter = newHTTPDebugWriter(conf.Debug)
 	logger := newHTTPDebugLogger(httpWriter)
-	client, err = reggie.NewClient(hostname,
-		reggie.WithDefaultName(namespace),
-		reggie.WithUsernamePassword(username, password),
+	client, err = reggie.NewClient(conf.RootURL,
+		reggie.WithDefaultName(conf.Namespace),
+		reggie.WithUsernamePassword(conf.Username, conf.Password),
 		reggie.WithDebug(true),
-		reggie.WithUserAgent("distribution-spec-conformance-tests"),
-		reggie.WithAuthScope(authScope),
-		reggie.WithInsecureSkipTLSVerify(true))
+		reggie.WithUserAgent(userAgent),
+		reggie.WithAuthScope(conf.AuthScope),
+		reggie.WithInsecureSkipTLSVerify(tlsConfig.InsecureSkipVerify))
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	client.SetLogger(logger)
+	client.SetDebugBodyLimit(debugBodyLimit)
 	client.SetCookieJar(nil)
+	client.GetClient().Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	client.SetTransport(&exchangeTransport{next: &warningTransport{next: client.GetClient().Transport}})
 
 	// create a unique config for each workflow category
 	for i := 0; i < numWorkflows; i++ {
@@ -232,7 +289,7 @@ func init() {
 		configBlobContentLength := strconv.Itoa(len(configBlobContent))
 		configBlobDigestRaw := godigest.FromBytes(configBlobContent)
 		configBlobDigest := configBlobDigestRaw.String()
-		if v := os.Getenv(envVarBlobDigest); v != "" {
+		if v := conf.Workflows.Pull.BlobDigest; v != "" {
 			configBlobDigest = v
 		}
 
@@ -248,14 +305,13 @@ func init() {
 		log.Fatal(err)
 	}
 
-	layerBlobDigestRaw := godigest.FromBytes(layerBlobData)
-	layerBlobDigest = layerBlobDigestRaw.String()
+	layerBlobDigest = godigest.FromBytes(layerBlobData).String()
 	layerBlobContentLength = fmt.Sprintf("%d", len(layerBlobData))
 
-	layers := []descriptor{{
+	layers := []v1.Descriptor{{
 		MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
 		Size:      int64(len(layerBlobData)),
-		Digest:    layerBlobDigestRaw,
+		Digest:    layerBlobDigest,
 	}}
 
 	// create a unique manifest for each workflow category
@@ -263,10 +319,12 @@ func init() {
 		manifest := manifest{
 			SchemaVersion: 2,
 			MediaType:     "application/vnd.oci.image.manifest.v1+json",
-			Config: descriptor{
-				MediaType:           "application/vnd.oci.image.config.v1+json",
-				Digest:              godigest.Digest(configs[i].Digest),
-				Size:                int64(len(configs[i].Content)),
+			Config: configDescriptor{
+				Descriptor: v1.Descriptor{
+					MediaType: "application/vnd.oci.image.config.v1+json",
+					Digest:    configs[i].Digest,
+					Size:      int64(len(configs[i].Content)),
+				},
 				Data:                configs[i].Content,    // must be the config content.
 				NewUnspecifiedField: []byte("hello world"), // content doesn't matter.
 			},
@@ -280,7 +338,7 @@ func init() {
 
 		manifestContentLength := strconv.Itoa(len(manifestContent))
 		manifestDigest := godigest.FromBytes(manifestContent).String()
-		if v := os.Getenv(envVarManifestDigest); v != "" {
+		if v := conf.Workflows.Pull.ManifestDigest; v != "" {
 			manifestDigest = v
 		}
 
@@ -294,14 +352,16 @@ func init() {
 	// used in push test
 	emptyLayerManifest := manifest{
 		SchemaVersion: 2,
-		Config: descriptor{
-			MediaType:           "application/vnd.oci.image.config.v1+json",
-			Digest:              godigest.Digest(configs[1].Digest),
-			Size:                int64(len(configs[1].Content)),
+		Config: configDescriptor{
+			Descriptor: v1.Descriptor{
+				MediaType: "application/vnd.oci.image.config.v1+json",
+				Digest:    configs[1].Digest,
+				Size:      int64(len(configs[1].Content)),
+			},
 			Data:                configs[1].Content,    // must be the config content.
 			NewUnspecifiedField: []byte("hello world"), // content doesn't matter.
 		},
-		Layers: []descriptor{},
+		Layers: []v1.Descriptor{},
 	}
 
 	emptyLayerManifestContent, err = json.MarshalIndent(&emptyLayerManifest, "", "\t")
@@ -310,6 +370,44 @@ func init() {
 	}
 	emptyLayerManifestDigest = string(godigest.FromBytes(emptyLayerManifestContent))
 
+	// used in push test, references a layer that is never pushed
+	unknownLayerDigest, unknownLayer := randomBlob(64, seed+4)
+	unknownBlobManifest := manifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Config: configDescriptor{Descriptor: v1.Descriptor{
+			MediaType: "application/vnd.oci.image.config.v1+json",
+			Digest:    configs[1].Digest,
+			Size:      int64(len(configs[1].Content)),
+		}},
+		Layers: []v1.Descriptor{{
+			MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+			Size:      int64(len(unknownLayer)),
+			Digest:    unknownLayerDigest.String(),
+		}},
+	}
+	unknownBlobManifestContent, err = json.MarshalIndent(&unknownBlobManifest, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// used in push test, pushed with the Content-Type of an image index
+	mismatchedTypeManifest := manifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Config: configDescriptor{Descriptor: v1.Descriptor{
+			MediaType: "application/vnd.oci.image.config.v1+json",
+			Digest:    configs[1].Digest,
+			Size:      int64(len(configs[1].Content)),
+		}},
+		Layers:      []v1.Descriptor{},
+		Annotations: map[string]string{"org.opencontainers.conformance.test": "mismatched content type"},
+	}
+	mismatchedTypeManifestContent, err = json.MarshalIndent(&mismatchedTypeManifest, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	nonexistentManifest = ".INVALID_MANIFEST_NAME"
 	invalidManifestContent = []byte("blablabla")
 
@@ -322,10 +420,10 @@ func init() {
 
 	// used in referrers test (artifacts with Subject field set)
 	emptyJSONBlob = []byte("{}")
-	emptyJSONDescriptor = descriptor{
+	emptyJSONDescriptor = v1.Descriptor{
 		MediaType: "application/vnd.oci.empty.v1+json",
 		Size:      int64(len(emptyJSONBlob)),
-		Digest:    godigest.FromBytes(emptyJSONBlob),
+		Digest:    godigest.FromBytes(emptyJSONBlob).String(),
 	}
 
 	testRefBlobA = []byte("NHL Peanut Butter on my NHL bagel")
@@ -347,17 +445,17 @@ func init() {
 	refsManifestAConfigArtifact := manifest{
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.manifest.v1+json",
-		Config: descriptor{
+		Config: configDescriptor{Descriptor: v1.Descriptor{
 			MediaType: testRefArtifactTypeA,
 			Size:      int64(len(testRefBlobA)),
-			Digest:    godigest.FromBytes(testRefBlobA),
-		},
-		Subject: &descriptor{
+			Digest:    godigest.FromBytes(testRefBlobA).String(),
+		}},
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[4].Content)),
-			Digest:    godigest.FromBytes(manifests[4].Content),
+			Digest:    godigest.FromBytes(manifests[4].Content).String(),
 		},
-		Layers: []descriptor{
+		Layers: []v1.Descriptor{
 			emptyJSONDescriptor,
 		},
 		Annotations: map[string]string{
@@ -376,17 +474,17 @@ func init() {
 	refsManifestBConfigArtifact := manifest{
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.manifest.v1+json",
-		Config: descriptor{
+		Config: configDescriptor{Descriptor: v1.Descriptor{
 			MediaType: testRefArtifactTypeB,
 			Size:      int64(len(testRefBlobB)),
-			Digest:    godigest.FromBytes(testRefBlobB),
-		},
-		Subject: &descriptor{
+			Digest:    godigest.FromBytes(testRefBlobB).String(),
+		}},
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[4].Content)),
-			Digest:    godigest.FromBytes(manifests[4].Content),
+			Digest:    godigest.FromBytes(manifests[4].Content).String(),
 		},
-		Layers: []descriptor{
+		Layers: []v1.Descriptor{
 			emptyJSONDescriptor,
 		},
 		Annotations: map[string]string{
@@ -407,17 +505,17 @@ func init() {
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.manifest.v1+json",
 		ArtifactType:  testRefArtifactTypeA,
-		Config:        emptyJSONDescriptor,
-		Subject: &descriptor{
+		Config:        configDescriptor{Descriptor: emptyJSONDescriptor},
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[4].Content)),
-			Digest:    godigest.FromBytes(manifests[4].Content),
+			Digest:    godigest.FromBytes(manifests[4].Content).String(),
 		},
-		Layers: []descriptor{
+		Layers: []v1.Descriptor{
 			{
 				MediaType: testRefArtifactTypeA,
 				Size:      int64(len(testRefBlobA)),
-				Digest:    godigest.FromBytes(testRefBlobA),
+				Digest:    godigest.FromBytes(testRefBlobA).String(),
 			},
 		},
 		Annotations: map[string]string{
@@ -437,17 +535,17 @@ func init() {
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.manifest.v1+json",
 		ArtifactType:  testRefArtifactTypeB,
-		Config:        emptyJSONDescriptor,
-		Subject: &descriptor{
+		Config:        configDescriptor{Descriptor: emptyJSONDescriptor},
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[4].Content)),
-			Digest:    godigest.FromBytes(manifests[4].Content),
+			Digest:    godigest.FromBytes(manifests[4].Content).String(),
 		},
-		Layers: []descriptor{
+		Layers: []v1.Descriptor{
 			{
 				MediaType: testRefArtifactTypeB,
 				Size:      int64(len(testRefBlobB)),
-				Digest:    godigest.FromBytes(testRefBlobB),
+				Digest:    godigest.FromBytes(testRefBlobB).String(),
 			},
 		},
 		Annotations: map[string]string{
@@ -468,17 +566,17 @@ func init() {
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.manifest.v1+json",
 		ArtifactType:  testRefArtifactTypeB,
-		Config:        emptyJSONDescriptor,
-		Subject: &descriptor{
+		Config:        configDescriptor{Descriptor: emptyJSONDescriptor},
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[3].Content)),
-			Digest:    godigest.FromBytes(manifests[3].Content),
+			Digest:    godigest.FromBytes(manifests[3].Content).String(),
 		},
-		Layers: []descriptor{
+		Layers: []v1.Descriptor{
 			{
 				MediaType: testRefArtifactTypeB,
 				Size:      int64(len(testRefBlobB)),
-				Digest:    godigest.FromBytes(testRefBlobB),
+				Digest:    godigest.FromBytes(testRefBlobB).String(),
 			},
 		},
 	}
@@ -491,26 +589,26 @@ func init() {
 	refsManifestCLayerArtifactDigest = godigest.FromBytes(refsManifestCLayerArtifactContent).String()
 
 	testRefArtifactTypeIndex = "application/vnd.food.stand"
-	refsIndexArtifact := index{
+	refsIndexArtifact := v1.Index{
 		SchemaVersion: 2,
 		MediaType:     "application/vnd.oci.image.index.v1+json",
 		ArtifactType:  testRefArtifactTypeIndex,
-		Manifests: []descriptor{
+		Manifests: []v1.Descriptor{
 			{
 				MediaType: "application/vnd.oci.image.manifest.v1+json",
 				Size:      int64(len(refsManifestAConfigArtifactContent)),
-				Digest:    godigest.FromBytes(refsManifestAConfigArtifactContent),
+				Digest:    godigest.FromBytes(refsManifestAConfigArtifactContent).String(),
 			},
 			{
 				MediaType: "application/vnd.oci.image.manifest.v1+json",
 				Size:      int64(len(refsManifestALayerArtifactContent)),
-				Digest:    godigest.FromBytes(refsManifestALayerArtifactContent),
+				Digest:    godigest.FromBytes(refsManifestALayerArtifactContent).String(),
 			},
 		},
-		Subject: &descriptor{
+		Subject: &v1.Descriptor{
 			MediaType: "application/vnd.oci.image.manifest.v1+json",
 			Size:      int64(len(manifests[4].Content)),
-			Digest:    godigest.FromBytes(manifests[4].Content),
+			Digest:    godigest.FromBytes(manifests[4].Content).String(),
 		},
 		Annotations: map[string]string{
 			testAnnotationKey: "test index",
@@ -524,6 +622,9 @@ func init() {
 	testAnnotationValues[refsIndexArtifactDigest] = refsIndexArtifact.Annotations[testAnnotationKey]
 
 	dummyDigest = godigest.FromString("hello world").String()
+	// the subject of the fallback index pushed by the referrers tag schema
+	// tests, distinct from the subjects of the referrers pushed in setup
+	fallbackSubjectDigest = godigest.FromString("referrers tag schema subject").String()
 
 	errorCodes = []string{
 		BLOB_UNKNOWN:          "BLOB_UNKNOWN",
@@ -541,36 +642,38 @@ func init() {
 		UNAUTHORIZED:          "UNAUTHORIZED",
 		DENIED:                "DENIED",
 		UNSUPPORTED:           "UNSUPPORTED",
+		TOOMANYREQUESTS:       "TOOMANYREQUESTS",
 	}
 
 	runPullSetup = true
 	runPushSetup = true
 	runContentDiscoverySetup = true
 	runContentManagementSetup = true
-	deleteManifestBeforeBlobs = true
 
-	if os.Getenv(envVarTagName) != "" &&
-		os.Getenv(envVarManifestDigest) != "" &&
-		os.Getenv(envVarBlobDigest) != "" {
+	if conf.Workflows.Pull.TagName != "" &&
+		conf.Workflows.Pull.ManifestDigest != "" &&
+		conf.Workflows.Pull.BlobDigest != "" {
 		runPullSetup = false
 	}
 
-	if os.Getenv(envVarTagList) != "" {
+	if len(conf.Workflows.ContentDiscovery.TagList) > 0 {
 		runContentDiscoverySetup = false
 	}
 
-	if v, ok := os.LookupEnv(envVarDeleteManifestBeforeBlobs); ok {
-		deleteManifestBeforeBlobs, _ = strconv.ParseBool(v)
-	}
-	automaticCrossmountVal := ""
-	automaticCrossmountVal, runAutomaticCrossmountTest = os.LookupEnv(envVarAutomaticCrossmount)
-	automaticCrossmountEnabled, _ = strconv.ParseBool(automaticCrossmountVal)
+	deleteManifestBeforeBlobs = conf.DeleteManifestBeforeBlobs
+	runAutomaticCrossmountTest = conf.Workflows.Push.AutomaticCrossmount != nil
+	automaticCrossmountEnabled = runAutomaticCrossmountTest && *conf.Workflows.Push.AutomaticCrossmount
+	runReferrersTagSchemaTest = conf.Workflows.ContentDiscovery.ReferrersTagSchema
 
-	if dir := os.Getenv(envVarReportDir); dir != "none" {
+	reportJUnitFilename, reportHTMLFilename, reportJSONFilename, reportHARFilename = "", "", "", ""
+	if dir := conf.Report.Dir; dir != "none" {
 		reportJUnitFilename = filepath.Join(dir, "junit.xml")
 		reportHTMLFilename = filepath.Join(dir, "report.html")
+		reportJSONFilename = filepath.Join(dir, "results.json")
+		reportHARFilename = filepath.Join(dir, "traffic.har")
 	}
 	suiteDescription = "OCI Distribution Conformance Tests"
+	return nil
 }
 
 func SkipIfDisabled(test int) {
@@ -602,9 +705,9 @@ func Warn(message string) {
 
 func generateSkipReport() string {
 	buf := new(bytes.Buffer)
-	fmt.Fprintf(buf, "you have skipped this test; if this is an error, check your environment variable settings:\n")
-	for k := range testMap {
-		fmt.Fprintf(buf, "\t%s=%s\n", k, os.Getenv(k))
+	fmt.Fprintf(buf, "you have skipped this test; if this is an error, check your configuration file or environment variable settings:\n")
+	for k, test := range testMap {
+		fmt.Fprintf(buf, "\t%s=%t\n", k, !userDisabled(test))
 	}
 	return buf.String()
 }
@@ -624,6 +727,69 @@ func getTagList(resp *reggie.Response) []string {
 	return tagList.Tags
 }
 
+func parseReferrersResponse(resp *reggie.Response) (*v1.ReferrersResponse, error) {
+	index := &v1.ReferrersResponse{}
+	if err := json.Unmarshal(resp.Body(), index); err != nil {
+		return nil, err
+	}
+	index.FiltersApplied = v1.ParseFiltersApplied(resp.Header().Get(v1.HeaderFiltersApplied))
+	return index, nil
+}
+
+// referrerDescriptor returns the descriptor listing a manifest with a subject
+// in a referrers response, as a client adds it to the referrers tag schema
+// fallback index.
+func referrerDescriptor(mediaType string, content []byte) (v1.Descriptor, error) {
+	m := manifest{}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return v1.Descriptor{}, err
+	}
+	artifactType := m.ArtifactType
+	if artifactType == "" {
+		artifactType = m.Config.MediaType
+	}
+	return v1.Descriptor{
+		MediaType:    mediaType,
+		Digest:       godigest.FromBytes(content).String(),
+		Size:         int64(len(content)),
+		ArtifactType: artifactType,
+		Annotations:  m.Annotations,
+	}, nil
+}
+
+// reggieDoer sends the requests of the specs-go clients with the suite's
+// client, so that they are authenticated and recorded like the others.
+type reggieDoer struct{}
+
+func (reggieDoer) Do(req *http.Request) (*http.Response, error) {
+	r := client.NewRequest(req.Method, req.URL.RequestURI())
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > 0 {
+			r.SetBody(body)
+		}
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	raw := *resp.RawResponse
+	raw.Body = io.NopCloser(bytes.NewReader(resp.Body()))
+	return &raw, nil
+}
+
+// newReferrersClient returns a client for the fallback indexes of the
+// default repository.
+func newReferrersClient() *referrers.Client {
+	return &referrers.Client{Doer: reggieDoer{}, Name: client.Config.DefaultName}
+}
+
 // Adapted from https://gist.github.com/dopey/c69559607800d2f2f90b1b1ed4e550fb
 func randomString(n int) string {
 	const letters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-"
@@ -650,6 +816,100 @@ func randomBlob(size int, seed int64) (godigest.Digest, []byte) {
 	return godigest.FromBytes(b), b
 }
 
+// newLargeManifest returns an image manifest of size bytes, made of as many
+// copies of the test layer as fit and an annotation filling the rest.
+func newLargeManifest(size int) TestBlob {
+	layer := v1.Descriptor{
+		MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+		Size:      int64(len(layerBlobData)),
+		Digest:    layerBlobDigest,
+	}
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.manifest.v1+json",
+		Config: configDescriptor{Descriptor: v1.Descriptor{
+			MediaType: "application/vnd.oci.image.config.v1+json",
+			Digest:    configs[1].Digest,
+			Size:      int64(len(configs[1].Content)),
+		}},
+		Annotations: map[string]string{"org.opencontainers.conformance.padding": ""},
+	}
+	marshal := func(v interface{}) []byte {
+		content, err := json.Marshal(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return content
+	}
+	// each layer takes its size and a comma
+	if n := (size - len(marshal(m))) / (len(marshal(layer)) + 1); n > 0 {
+		m.Layers = make([]v1.Descriptor, n)
+		for i := range m.Layers {
+			m.Layers[i] = layer
+		}
+	}
+	if pad := size - len(marshal(m)); pad > 0 {
+		m.Annotations["org.opencontainers.conformance.padding"] = strings.Repeat("x", pad)
+	}
+	content := marshal(m)
+	return TestBlob{
+		Content:       content,
+		ContentLength: strconv.Itoa(len(content)),
+		Digest:        godigest.FromBytes(content).String(),
+	}
+}
+
+// blobChunk is a part of a blob sent in a chunked upload.
+type blobChunk struct {
+	Content       []byte
+	ContentLength string
+	Range         string
+}
+
+// chunkedBlob is a blob and the chunks it is uploaded in.
+type chunkedBlob struct {
+	Content []byte
+	Digest  string
+	Chunks  []blobChunk
+}
+
+// newChunkedBlob returns a reproducible random blob made of n chunks of
+// chunkSize bytes followed by a shorter last chunk, so that every chunk but the
+// last honours a minimum chunk length of chunkSize.
+func newChunkedBlob(chunkSize, n int, seed int64) chunkedBlob {
+	dig, blob := randomBlob(n*chunkSize+chunkSize/2+1, seed)
+	b := chunkedBlob{Content: blob, Digest: dig.String()}
+	for start := 0; start < len(blob); start += chunkSize {
+		end := start + chunkSize
+		if end > len(blob) {
+			end = len(blob)
+		}
+		b.Chunks = append(b.Chunks, blobChunk{
+			Content:       blob[start:end],
+			ContentLength: strconv.Itoa(end - start),
+			Range:         fmt.Sprintf("%d-%d", start, end-1),
+		})
+	}
+	return b
+}
+
+// chunkMinLength returns the minimum chunk length advertised in the
+// OCI-Chunk-Min-Length header of resp, or 0 when there is none.
+func chunkMinLength(resp *reggie.Response) (int, error) {
+	v := resp.Header().Get("OCI-Chunk-Min-Length")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err == nil && n <= 0 {
+		err = fmt.Errorf("not a positive length")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid OCI-Chunk-Min-Length %q: %v", v, err)
+	}
+	return n, nil
+}
+
 func setupChunkedBlob(size int) {
 	dig, blob := randomBlob(size, seed+2)
 	testBlobB = blob
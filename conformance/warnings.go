@@ -0,0 +1,109 @@
+package conformance
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	g "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/opencontainers/distribution-spec/specs-go/warning"
+)
+
+const (
+	// reportEntryWarning names the report entries holding the warnings a
+	// registry sent during a spec.
+	reportEntryWarning = "Registry warning"
+
+	// reportEntryMalformedWarning names the report entries holding the
+	// Warning headers that break the format rules.
+	reportEntryMalformedWarning = "Malformed registry warning"
+)
+
+type (
+	// warningResponse holds the Warning headers of a single response.
+	warningResponse struct {
+		Method string
+		URL    string
+		Values []string
+	}
+
+	// warningLog keeps the Warning headers of every response of the run.
+	warningLog struct {
+		mu        sync.Mutex
+		responses []warningResponse
+	}
+)
+
+var (
+	// warningCollector collects the Warning headers of every response of the
+	// current spec.
+	warningCollector = warning.NewCollector()
+
+	// receivedWarnings holds the Warning headers of every response of the run,
+	// checked by the Warnings workflow.
+	receivedWarnings = &warningLog{}
+)
+
+func (l *warningLog) add(resp *http.Response) {
+	values := resp.Header.Values(warning.Header)
+	if len(values) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.responses = append(l.responses, warningResponse{
+		Method: resp.Request.Method,
+		URL:    resp.Request.URL.String(),
+		Values: values,
+	})
+}
+
+func (l *warningLog) all() []warningResponse {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]warningResponse{}, l.responses...)
+}
+
+// warningTransport feeds the Warning headers of every response to
+// warningCollector and receivedWarnings.
+type warningTransport struct {
+	next http.RoundTripper
+}
+
+func (t *warningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		_ = warningCollector.Add(resp.Header)
+		receivedWarnings.add(resp)
+	}
+	return resp, err
+}
+
+// reportWarnings attaches the warnings received during the current spec to
+// its report, flags any that break the format rules, and resets the collector
+// for the next spec.
+func reportWarnings() {
+	for _, w := range warningCollector.Warnings() {
+		g.AddReportEntry(reportEntryWarning, w.String())
+	}
+	for _, err := range warningCollector.Errors() {
+		g.AddReportEntry(reportEntryMalformedWarning, err.Error())
+		Warn(fmt.Sprintf("registry sent a malformed Warning header: %v", err))
+	}
+	warningCollector.Reset()
+}
+
+// specWarnings returns the warnings attached to a spec report by
+// reportWarnings, and the violations of the format rules among them.
+func specWarnings(r types.SpecReport) (received, malformed []string) {
+	for _, e := range r.ReportEntries {
+		switch e.Name {
+		case reportEntryWarning:
+			received = append(received, e.StringRepresentation())
+		case reportEntryMalformedWarning:
+			malformed = append(malformed, e.StringRepresentation())
+		}
+	}
+	return received, malformed
+}
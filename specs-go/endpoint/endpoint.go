@@ -0,0 +1,246 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package endpoint describes the API endpoints listed in /spec.md#endpoints.
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ID identifies an endpoint, such as "end-1".
+type ID string
+
+// Endpoint IDs as listed in the specification.
+const (
+	End1   ID = "end-1"
+	End2   ID = "end-2"
+	End3   ID = "end-3"
+	End4a  ID = "end-4a"
+	End4b  ID = "end-4b"
+	End5   ID = "end-5"
+	End6   ID = "end-6"
+	End7   ID = "end-7"
+	End8a  ID = "end-8a"
+	End8b  ID = "end-8b"
+	End9   ID = "end-9"
+	End10  ID = "end-10"
+	End11  ID = "end-11"
+	End12a ID = "end-12a"
+	End12b ID = "end-12b"
+	End13  ID = "end-13"
+)
+
+// Endpoint is a row of the endpoints table.
+type Endpoint struct {
+	// ID is the identifier of the endpoint.
+	ID ID
+
+	// Methods lists the HTTP methods of the endpoint.
+	Methods []string
+
+	// PathTemplate is the path with <name>, <digest> and <reference>
+	// placeholders, without the query string.
+	PathTemplate string
+
+	// QueryParams lists the query parameters that distinguish the endpoint.
+	QueryParams []string
+
+	// Success lists the status codes of a successful response.
+	Success []int
+
+	// Failure lists the status codes of a failed response.
+	Failure []int
+
+	pathRegexp *regexp.Regexp
+}
+
+var endpoints = []Endpoint{
+	{ID: End1, Methods: []string{http.MethodGet}, PathTemplate: "/v2/",
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound, http.StatusUnauthorized}},
+	{ID: End2, Methods: []string{http.MethodGet, http.MethodHead}, PathTemplate: "/v2/<name>/blobs/<digest>",
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound}},
+	{ID: End3, Methods: []string{http.MethodGet, http.MethodHead}, PathTemplate: "/v2/<name>/manifests/<reference>",
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound}},
+	{ID: End4a, Methods: []string{http.MethodPost}, PathTemplate: "/v2/<name>/blobs/uploads/",
+		Success: []int{http.StatusAccepted}, Failure: []int{http.StatusNotFound}},
+	{ID: End4b, Methods: []string{http.MethodPost}, PathTemplate: "/v2/<name>/blobs/uploads/", QueryParams: []string{"digest"},
+		Success: []int{http.StatusCreated, http.StatusAccepted}, Failure: []int{http.StatusNotFound, http.StatusBadRequest}},
+	{ID: End5, Methods: []string{http.MethodPatch}, PathTemplate: "/v2/<name>/blobs/uploads/<reference>",
+		Success: []int{http.StatusAccepted}, Failure: []int{http.StatusNotFound, http.StatusRequestedRangeNotSatisfiable}},
+	{ID: End6, Methods: []string{http.MethodPut}, PathTemplate: "/v2/<name>/blobs/uploads/<reference>", QueryParams: []string{"digest"},
+		Success: []int{http.StatusCreated}, Failure: []int{http.StatusNotFound, http.StatusBadRequest}},
+	{ID: End7, Methods: []string{http.MethodPut}, PathTemplate: "/v2/<name>/manifests/<reference>",
+		Success: []int{http.StatusCreated}, Failure: []int{http.StatusNotFound, http.StatusRequestEntityTooLarge}},
+	{ID: End8a, Methods: []string{http.MethodGet}, PathTemplate: "/v2/<name>/tags/list",
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound}},
+	{ID: End8b, Methods: []string{http.MethodGet}, PathTemplate: "/v2/<name>/tags/list", QueryParams: []string{"n", "last"},
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound}},
+	{ID: End9, Methods: []string{http.MethodDelete}, PathTemplate: "/v2/<name>/manifests/<reference>",
+		Success: []int{http.StatusAccepted}, Failure: []int{http.StatusNotFound, http.StatusBadRequest, http.StatusMethodNotAllowed}},
+	{ID: End10, Methods: []string{http.MethodDelete}, PathTemplate: "/v2/<name>/blobs/<digest>",
+		Success: []int{http.StatusAccepted}, Failure: []int{http.StatusNotFound, http.StatusBadRequest, http.StatusMethodNotAllowed}},
+	{ID: End11, Methods: []string{http.MethodPost}, PathTemplate: "/v2/<name>/blobs/uploads/", QueryParams: []string{"mount", "from"},
+		Success: []int{http.StatusCreated, http.StatusAccepted}, Failure: []int{http.StatusNotFound}},
+	{ID: End12a, Methods: []string{http.MethodGet}, PathTemplate: "/v2/<name>/referrers/<digest>",
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound, http.StatusBadRequest}},
+	{ID: End12b, Methods: []string{http.MethodGet}, PathTemplate: "/v2/<name>/referrers/<digest>", QueryParams: []string{"artifactType"},
+		Success: []int{http.StatusOK}, Failure: []int{http.StatusNotFound, http.StatusBadRequest}},
+	{ID: End13, Methods: []string{http.MethodGet}, PathTemplate: "/v2/<name>/blobs/uploads/<reference>",
+		Success: []int{http.StatusNoContent}, Failure: []int{http.StatusNotFound}},
+}
+
+var placeholderRegexp = regexp.MustCompile(`<(name|digest|reference)>`)
+
+func init() {
+	for i := range endpoints {
+		e := &endpoints[i]
+		pattern := regexp.QuoteMeta(e.PathTemplate)
+		pattern = strings.ReplaceAll(pattern, "<name>", `(?P<name>.+)`)
+		pattern = strings.ReplaceAll(pattern, "<digest>", `(?P<digest>[^/]+)`)
+		pattern = strings.ReplaceAll(pattern, "<reference>", `(?P<reference>[^/]+)`)
+		e.pathRegexp = regexp.MustCompile(`^` + pattern + `$`)
+	}
+}
+
+// All returns every endpoint in the order of the specification.
+func All() []Endpoint {
+	return append([]Endpoint{}, endpoints...)
+}
+
+// Lookup returns the endpoint with the given ID.
+func Lookup(id ID) (Endpoint, bool) {
+	for _, e := range endpoints {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
+
+// MustLookup is like Lookup but panics for an unknown ID.
+func MustLookup(id ID) Endpoint {
+	e, ok := Lookup(id)
+	if !ok {
+		panic(fmt.Sprintf("endpoint: unknown ID %q", id))
+	}
+	return e
+}
+
+// Match returns the endpoint a request is addressed to. When several
+// endpoints share a method and path, the one with the most matching query
+// parameters wins, so a POST with ?mount= matches end-11 rather than end-4a.
+func Match(method string, u *url.URL) (Endpoint, bool) {
+	var (
+		best      Endpoint
+		bestScore = -1
+	)
+	query := u.Query()
+	for _, e := range endpoints {
+		if !e.hasMethod(method) || !e.pathRegexp.MatchString(u.Path) {
+			continue
+		}
+		score := 0
+		for _, p := range e.QueryParams {
+			if query.Has(p) {
+				score++
+			}
+		}
+		if len(e.QueryParams) > 0 && score == 0 {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// PathVars returns the placeholder values of path, or nil if path does not
+// match the template of the endpoint.
+func (e Endpoint) PathVars(path string) map[string]string {
+	m := e.pathRegexp.FindStringSubmatch(path)
+	if m == nil {
+		return nil
+	}
+	vars := map[string]string{}
+	for i, n := range e.pathRegexp.SubexpNames() {
+		if n != "" {
+			vars[n] = m[i]
+		}
+	}
+	return vars
+}
+
+// Path fills the placeholders of the path template with vars and appends
+// query as query string. Every placeholder must be set.
+func (e Endpoint) Path(vars map[string]string, query url.Values) (string, error) {
+	var missing []string
+	p := placeholderRegexp.ReplaceAllStringFunc(e.PathTemplate, func(ph string) string {
+		key := ph[1 : len(ph)-1]
+		v, ok := vars[key]
+		if !ok || v == "" {
+			missing = append(missing, ph)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("endpoint %s: missing value for %s", e.ID, strings.Join(missing, ", "))
+	}
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+	return p, nil
+}
+
+// IsSuccess reports whether status is a success status of the endpoint.
+func (e Endpoint) IsSuccess(status int) bool {
+	return containsInt(e.Success, status)
+}
+
+// IsFailure reports whether status is a failure status of the endpoint.
+func (e Endpoint) IsFailure(status int) bool {
+	return containsInt(e.Failure, status)
+}
+
+// CheckStatus returns an error when status is neither a success nor a
+// failure status of the endpoint.
+func (e Endpoint) CheckStatus(status int) error {
+	if e.IsSuccess(status) || e.IsFailure(status) {
+		return nil
+	}
+	return fmt.Errorf("endpoint %s: unexpected status %d, want one of %v or %v", e.ID, status, e.Success, e.Failure)
+}
+
+func (e Endpoint) hasMethod(method string) bool {
+	for _, m := range e.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(l []int, i int) bool {
+	for _, v := range l {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
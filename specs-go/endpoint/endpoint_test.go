@@ -0,0 +1,76 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	const digest = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	tests := []struct {
+		method string
+		url    string
+		want   ID
+	}{
+		{http.MethodGet, "/v2/", End1},
+		{http.MethodHead, "/v2/myorg/myrepo/blobs/" + digest, End2},
+		{http.MethodGet, "/v2/myorg/myrepo/manifests/latest", End3},
+		{http.MethodPost, "/v2/myorg/myrepo/blobs/uploads/", End4a},
+		{http.MethodPost, "/v2/myorg/myrepo/blobs/uploads/?digest=" + digest, End4b},
+		{http.MethodPatch, "/v2/myorg/myrepo/blobs/uploads/abc-123", End5},
+		{http.MethodPut, "/v2/myorg/myrepo/blobs/uploads/abc-123?digest=" + digest, End6},
+		{http.MethodPut, "/v2/myorg/myrepo/manifests/latest", End7},
+		{http.MethodGet, "/v2/myorg/myrepo/tags/list", End8a},
+		{http.MethodGet, "/v2/myorg/myrepo/tags/list?n=2&last=a", End8b},
+		{http.MethodGet, "/v2/myorg/myrepo/tags/list?last=a", End8b},
+		{http.MethodDelete, "/v2/myorg/myrepo/manifests/" + digest, End9},
+		{http.MethodDelete, "/v2/myorg/myrepo/blobs/" + digest, End10},
+		{http.MethodPost, "/v2/myorg/myrepo/blobs/uploads/?mount=" + digest + "&from=other", End11},
+		{http.MethodPost, "/v2/myorg/myrepo/blobs/uploads/?mount=" + digest, End11},
+		{http.MethodGet, "/v2/myorg/myrepo/referrers/" + digest, End12a},
+		{http.MethodGet, "/v2/myorg/myrepo/referrers/" + digest + "?artifactType=a%2Fb", End12b},
+		{http.MethodGet, "/v2/myorg/myrepo/blobs/uploads/abc-123", End13},
+		{http.MethodPost, "/v2/myorg/myrepo/manifests/latest", ""},
+		{http.MethodGet, "/v1/", ""},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := Match(tt.method, u)
+		if got.ID != tt.want || ok != (tt.want != "") {
+			t.Errorf("Match(%s, %s) = %q, %v, want %q", tt.method, tt.url, got.ID, ok, tt.want)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	e := MustLookup(End8b)
+	got, err := e.Path(map[string]string{"name": "myorg/myrepo"}, url.Values{"n": {"2"}})
+	if err != nil || got != "/v2/myorg/myrepo/tags/list?n=2" {
+		t.Errorf("Path() = %q, %v", got, err)
+	}
+	if _, err := MustLookup(End2).Path(map[string]string{"name": "myrepo"}, nil); err == nil {
+		t.Errorf("Path() with missing <digest> should fail")
+	}
+	vars := MustLookup(End3).PathVars("/v2/myorg/myrepo/manifests/latest")
+	if vars["name"] != "myorg/myrepo" || vars["reference"] != "latest" {
+		t.Errorf("PathVars() = %v", vars)
+	}
+}
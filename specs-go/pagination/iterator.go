@@ -0,0 +1,277 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+var (
+	// ErrRepeatedCursor is returned when a next page points at a page that
+	// was already requested, or repeats the value of the last parameter.
+	ErrRepeatedCursor = errors.New("pagination: cursor repeated")
+
+	// ErrNoProgress is returned when a page announces a next page but holds
+	// no entry that was not returned before.
+	ErrNoProgress = errors.New("pagination: page did not advance")
+)
+
+// Doer sends HTTP requests. *http.Client implements Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// decodeFunc decodes a page and returns its entries along with a key per
+// entry used to detect pages that do not advance.
+type decodeFunc[T any] func(body []byte) (items []T, keys []string, err error)
+
+// Iterator yields the entries of a paginated listing, requesting the pages
+// one at a time.
+type Iterator[T any] struct {
+	client Doer
+	req    *http.Request
+	decode decodeFunc[T]
+
+	// lastParam returns the value of the last parameter for the next page,
+	// for listings that support it, when no Link header is present.
+	lastParam func(req *http.Request, items []T) (string, bool)
+
+	// onPage is called with every successful response.
+	onPage func(resp *http.Response)
+
+	buf      []T
+	cur      T
+	err      error
+	finished bool
+	pages    int
+	visited  map[string]bool
+	lasts    map[string]bool
+	seen     map[string]bool
+}
+
+func newIterator[T any](client Doer, req *http.Request, decode decodeFunc[T]) *Iterator[T] {
+	return &Iterator[T]{
+		client:  client,
+		req:     req,
+		decode:  decode,
+		visited: map[string]bool{},
+		lasts:   map[string]bool{},
+		seen:    map[string]bool{},
+	}
+}
+
+// Next advances to the next entry, fetching a new page when needed. It
+// returns false when there are no more entries or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	for len(it.buf) == 0 {
+		if it.finished || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Value returns the current entry.
+func (it *Iterator[T]) Value() T {
+	return it.cur
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Pages returns the number of pages fetched so far.
+func (it *Iterator[T]) Pages() int {
+	return it.pages
+}
+
+// All drains the iterator and returns every entry.
+func (it *Iterator[T]) All() ([]T, error) {
+	var all []T
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
+
+func (it *Iterator[T]) fetch() {
+	req := it.req
+	if it.visited[req.URL.String()] {
+		it.err = fmt.Errorf("%w: %s requested twice", ErrRepeatedCursor, req.URL)
+		return
+	}
+	it.visited[req.URL.String()] = true
+	if last := req.URL.Query().Get("last"); last != "" {
+		if it.lasts[last] {
+			it.err = fmt.Errorf("%w: last=%q requested twice", ErrRepeatedCursor, last)
+			return
+		}
+		it.lasts[last] = true
+	}
+
+	resp, err := it.client.Do(req)
+	if err != nil {
+		it.err = err
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		it.err = err
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		it.err = statusError(resp, body)
+		return
+	}
+	it.pages++
+	if it.onPage != nil {
+		it.onPage(resp)
+	}
+
+	items, keys, err := it.decode(body)
+	if err != nil {
+		it.err = err
+		return
+	}
+	advanced := false
+	for i, item := range items {
+		if it.seen[keys[i]] {
+			continue
+		}
+		it.seen[keys[i]] = true
+		advanced = true
+		it.buf = append(it.buf, item)
+	}
+
+	next, err := NextRequest(req, resp)
+	if err != nil {
+		it.err = err
+		return
+	}
+	if next == nil && it.lastParam != nil {
+		if last, ok := it.lastParam(req, items); ok {
+			q := req.URL.Query()
+			q.Set("last", last)
+			u := *req.URL
+			u.RawQuery = q.Encode()
+			next = withURL(req, &u)
+		}
+	}
+	if next == nil {
+		it.finished = true
+		return
+	}
+	if !advanced {
+		it.err = fmt.Errorf("%w: %s returned no new entries", ErrNoProgress, req.URL)
+		return
+	}
+	it.req = next
+}
+
+func statusError(resp *http.Response, body []byte) error {
+	errResp := &v1.ErrorResponse{}
+	if err := json.Unmarshal(body, errResp); err == nil && len(errResp.Errors) > 0 {
+		return fmt.Errorf("pagination: %s returned %d: %w", resp.Request.URL, resp.StatusCode, errResp)
+	}
+	return fmt.Errorf("pagination: %s returned %d", resp.Request.URL, resp.StatusCode)
+}
+
+// NewTagIterator returns an iterator over the tags listed by req, a GET
+// request to /v2/<name>/tags/list. The Link header is followed when present.
+// Otherwise, when req sets n and a full page is returned, the next page is
+// requested with the last parameter.
+func NewTagIterator(client Doer, req *http.Request) *Iterator[string] {
+	return newTagIterator(client, req, new(string))
+}
+
+func newTagIterator(client Doer, req *http.Request, name *string) *Iterator[string] {
+	it := newIterator(client, req, func(body []byte) ([]string, []string, error) {
+		tl := &v1.TagList{}
+		if err := json.Unmarshal(body, tl); err != nil {
+			return nil, nil, fmt.Errorf("pagination: decoding tag list: %w", err)
+		}
+		if *name == "" {
+			*name = tl.Name
+		}
+		return tl.Tags, tl.Tags, nil
+	})
+	it.lastParam = func(req *http.Request, tags []string) (string, bool) {
+		n, err := strconv.Atoi(req.URL.Query().Get("n"))
+		if err != nil || n <= 0 || len(tags) < n {
+			return "", false
+		}
+		return tags[len(tags)-1], true
+	}
+	return it
+}
+
+// NewReferrersIterator returns an iterator over the descriptors listed by
+// req, a GET request to /v2/<name>/referrers/<digest>. Pages are followed
+// with the Link header.
+func NewReferrersIterator(client Doer, req *http.Request) *Iterator[v1.Descriptor] {
+	return newIterator(client, req, func(body []byte) ([]v1.Descriptor, []string, error) {
+		rr := &v1.ReferrersResponse{}
+		if err := json.Unmarshal(body, rr); err != nil {
+			return nil, nil, fmt.Errorf("pagination: decoding referrers response: %w", err)
+		}
+		keys := make([]string, 0, len(rr.Manifests))
+		for _, d := range rr.Manifests {
+			keys = append(keys, d.Digest)
+		}
+		return rr.Manifests, keys, nil
+	})
+}
+
+// FetchTags follows every page of req and returns the combined tag list.
+func FetchTags(client Doer, req *http.Request) (*v1.TagList, error) {
+	tl := &v1.TagList{Tags: []string{}}
+	tags, err := newTagIterator(client, req, &tl.Name).All()
+	if err != nil {
+		return nil, err
+	}
+	tl.Tags = append(tl.Tags, tags...)
+	return tl, nil
+}
+
+// FetchReferrers follows every page of req and returns the merged referrers
+// response. The OCI-Filters-Applied header of every page is taken into
+// account, see v1.MergeReferrersResponses.
+func FetchReferrers(client Doer, req *http.Request) (*v1.ReferrersResponse, error) {
+	it := NewReferrersIterator(client, req)
+	var pages []*v1.ReferrersResponse
+	it.onPage = func(resp *http.Response) {
+		pages = append(pages, &v1.ReferrersResponse{
+			FiltersApplied: v1.ParseFiltersApplied(resp.Header.Get(v1.HeaderFiltersApplied)),
+		})
+	}
+	manifests, err := it.All()
+	if err != nil {
+		return nil, err
+	}
+	merged := v1.MergeReferrersResponses(pages...)
+	merged.Manifests = append(merged.Manifests, manifests...)
+	return merged, nil
+}
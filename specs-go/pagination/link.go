@@ -0,0 +1,169 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pagination follows the paginated responses of the tag listing
+// (end-8b) and referrers (end-12a) endpoints.
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Link is a single link-value of an RFC 5988 Link header.
+type Link struct {
+	// URL is the target of the link, as found between < and >.
+	URL string
+
+	// Rel holds the relation types of the link.
+	Rel []string
+
+	// Params holds every other link parameter, keyed by lowercase name.
+	Params map[string]string
+}
+
+// HasRel reports whether the link has the relation type rel.
+func (l Link) HasRel(rel string) bool {
+	for _, r := range l.Rel {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLinkHeader parses the values of one or more Link headers.
+func ParseLinkHeader(values []string) ([]Link, error) {
+	var links []Link
+	for _, v := range values {
+		s := strings.TrimSpace(v)
+		for s != "" {
+			if s[0] != '<' {
+				return nil, fmt.Errorf("pagination: malformed Link header %q: expected '<'", v)
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("pagination: malformed Link header %q: missing '>'", v)
+			}
+			link := Link{URL: strings.TrimSpace(s[1:end]), Params: map[string]string{}}
+			s = strings.TrimSpace(s[end+1:])
+			for strings.HasPrefix(s, ";") {
+				var name, value string
+				var err error
+				name, value, s, err = parseLinkParam(strings.TrimSpace(s[1:]))
+				if err != nil {
+					return nil, fmt.Errorf("pagination: malformed Link header %q: %w", v, err)
+				}
+				if name == "rel" {
+					link.Rel = append(link.Rel, strings.Fields(value)...)
+				} else {
+					link.Params[name] = value
+				}
+			}
+			links = append(links, link)
+			switch {
+			case s == "":
+			case s[0] == ',':
+				s = strings.TrimSpace(s[1:])
+			default:
+				return nil, fmt.Errorf("pagination: malformed Link header %q: unexpected %q", v, s)
+			}
+		}
+	}
+	return links, nil
+}
+
+// parseLinkParam reads a single name[=value] parameter from the start of s
+// and returns the remainder of s.
+func parseLinkParam(s string) (name, value, rest string, err error) {
+	i := strings.IndexAny(s, "=;,")
+	if i < 0 {
+		return strings.ToLower(strings.TrimSpace(s)), "", "", nil
+	}
+	name = strings.ToLower(strings.TrimSpace(s[:i]))
+	if name == "" {
+		return "", "", "", fmt.Errorf("empty parameter name")
+	}
+	if s[i] != '=' {
+		return name, "", strings.TrimSpace(s[i:]), nil
+	}
+	s = strings.TrimSpace(s[i+1:])
+	if strings.HasPrefix(s, `"`) {
+		var b strings.Builder
+		for j := 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+				if j < len(s) {
+					b.WriteByte(s[j])
+				}
+			case '"':
+				return name, b.String(), strings.TrimSpace(s[j+1:]), nil
+			default:
+				b.WriteByte(s[j])
+			}
+		}
+		return "", "", "", fmt.Errorf("unterminated quoted value for %q", name)
+	}
+	j := strings.IndexAny(s, ";,")
+	if j < 0 {
+		return name, strings.TrimSpace(s), "", nil
+	}
+	return name, strings.TrimSpace(s[:j]), strings.TrimSpace(s[j:]), nil
+}
+
+// NextLink returns the target of the rel="next" link found in h, or an empty
+// string when there is none.
+func NextLink(h http.Header) (string, error) {
+	links, err := ParseLinkHeader(h.Values("Link"))
+	if err != nil {
+		return "", err
+	}
+	for _, l := range links {
+		if l.HasRel("next") {
+			return l.URL, nil
+		}
+	}
+	return "", nil
+}
+
+// NextRequest builds the request for the page following resp, which answered
+// prev. The rel="next" link is resolved against the URL of prev, and the
+// headers of prev are kept, except the credentials when the link points to
+// another host. It returns nil when resp has no next link.
+func NextRequest(prev *http.Request, resp *http.Response) (*http.Request, error) {
+	next, err := NextLink(resp.Header)
+	if err != nil || next == "" {
+		return nil, err
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("pagination: invalid next link %q: %w", next, err)
+	}
+	return withURL(prev, prev.URL.ResolveReference(u)), nil
+}
+
+func withURL(prev *http.Request, u *url.URL) *http.Request {
+	req := prev.Clone(prev.Context())
+	req.URL = u
+	if u.Host != prev.URL.Host {
+		// credentials for the registry must not reach the linked host
+		req.Host = ""
+		req.Header.Del("Authorization")
+		req.Header.Del("Cookie")
+	}
+	return req
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pagination
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []Link
+		wantErr bool
+	}{
+		{
+			in:   []string{`</v2/repo/tags/list?n=2&last=b>; rel="next"`},
+			want: []Link{{URL: "/v2/repo/tags/list?n=2&last=b", Rel: []string{"next"}, Params: map[string]string{}}},
+		},
+		{
+			in: []string{`<https://r.example.org/a>; rel=prev; title="a, b", <https://r.example.org/c>;rel="next last"`},
+			want: []Link{
+				{URL: "https://r.example.org/a", Rel: []string{"prev"}, Params: map[string]string{"title": "a, b"}},
+				{URL: "https://r.example.org/c", Rel: []string{"next", "last"}, Params: map[string]string{}},
+			},
+		},
+		{
+			in: []string{`</a>; rel="prev"`, `</b>; rel="next"`},
+			want: []Link{
+				{URL: "/a", Rel: []string{"prev"}, Params: map[string]string{}},
+				{URL: "/b", Rel: []string{"next"}, Params: map[string]string{}},
+			},
+		},
+		{in: []string{`/a; rel="next"`}, wantErr: true},
+		{in: []string{`</a; rel="next"`}, wantErr: true},
+		{in: []string{`</a>; rel="next`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLinkHeader(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLinkHeader(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLinkHeader(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// tagServer serves a sorted tag list with the given paging behaviour.
+func tagServer(tags []string, link bool, ignoreLast bool) *httptest.Server {
+	sort.Strings(tags)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		start := 0
+		if last := q.Get("last"); last != "" && !ignoreLast {
+			start = sort.SearchStrings(tags, last) + 1
+		}
+		end := len(tags)
+		if n, err := strconv.Atoi(q.Get("n")); err == nil && start+n < end {
+			end = start + n
+			if link {
+				w.Header().Set("Link", `<`+r.URL.Path+`?n=`+q.Get("n")+`&last=`+tags[end-1]+`>; rel="next"`)
+			}
+		}
+		if start > end {
+			start = end
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "repo", "tags": tags[start:end]})
+	}))
+}
+
+func TestFetchTags(t *testing.T) {
+	tags := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name       string
+		link       bool
+		ignoreLast bool
+		wantErr    error
+	}{
+		{name: "link header", link: true},
+		{name: "last parameter", link: false},
+		{name: "ignored last", link: true, ignoreLast: true, wantErr: ErrNoProgress},
+	}
+	for _, tt := range tests {
+		srv := tagServer(append([]string{}, tags...), tt.link, tt.ignoreLast)
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v2/repo/tags/list?n=2", nil)
+		got, err := FetchTags(srv.Client(), req)
+		srv.Close()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: FetchTags() error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (!reflect.DeepEqual(got.Tags, tags) || got.Name != "repo") {
+			t.Errorf("%s: FetchTags() = %v", tt.name, got)
+		}
+	}
+}
+
+func TestRepeatedCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every page, including the second one, links to the second page
+		w.Header().Set("Link", `</v2/repo/referrers/sha256:a?page=2>; rel="next"`)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"schemaVersion": 2,
+			"manifests":     []map[string]string{{"digest": "sha256:" + r.URL.Query().Get("page")}},
+		})
+	}))
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v2/repo/referrers/sha256:a", nil)
+	it := NewReferrersIterator(srv.Client(), req)
+	got, err := it.All()
+	if !errors.Is(err, ErrRepeatedCursor) {
+		t.Fatalf("All() error = %v, want %v", err, ErrRepeatedCursor)
+	}
+	if len(got) != 2 || it.Pages() != 2 {
+		t.Errorf("All() = %v after %d pages", got, it.Pages())
+	}
+}
+
+func TestNextRequestCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		link     string
+		wantAuth bool
+	}{
+		{name: "relative link", link: "/v2/repo/tags/list?last=b", wantAuth: true},
+		{name: "same host", link: "https://registry.example.org/v2/repo/tags/list?last=b", wantAuth: true},
+		{name: "other host", link: "https://other.example.org/v2/repo/tags/list?last=b", wantAuth: false},
+		{name: "other port", link: "https://registry.example.org:8443/v2/repo/tags/list?last=b", wantAuth: false},
+	}
+	for _, tt := range tests {
+		prev, _ := http.NewRequest(http.MethodGet, "https://registry.example.org/v2/repo/tags/list?n=2", nil)
+		prev.Header.Set("Authorization", "Bearer secret")
+		prev.Header.Set("Cookie", "session=secret")
+		prev.Header.Set("Accept", "application/json")
+		resp := &http.Response{Header: http.Header{"Link": {"<" + tt.link + `>; rel="next"`}}}
+		next, err := NextRequest(prev, resp)
+		if err != nil {
+			t.Errorf("%s: NextRequest() error = %v", tt.name, err)
+			continue
+		}
+		for _, h := range []string{"Authorization", "Cookie"} {
+			if got := next.Header.Get(h) != ""; got != tt.wantAuth {
+				t.Errorf("%s: %s kept = %v, want %v", tt.name, h, got, tt.wantAuth)
+			}
+		}
+		if next.Header.Get("Accept") == "" {
+			t.Errorf("%s: Accept header dropped", tt.name)
+		}
+		if prev.Header.Get("Authorization") == "" {
+			t.Errorf("%s: Authorization removed from the previous request", tt.name)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reference validates and parses repository names, tags and digests
+// following the grammars in /spec.md#pulling-manifests.
+package reference
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+const (
+	// NameComponentPattern matches a single path component of a <name>.
+	NameComponentPattern = `[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*`
+
+	// NamePattern matches a repository <name>.
+	NamePattern = NameComponentPattern + `(\/` + NameComponentPattern + `)*`
+
+	// TagPattern matches a <reference> that is a tag.
+	TagPattern = `[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}`
+
+	// DigestPattern matches a digest as defined by the OCI image-spec.
+	DigestPattern = `[a-z0-9]+([+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+`
+
+	// TagMaxLength is the maximum length of a tag.
+	TagMaxLength = 128
+
+	// NameMaxLength is the length many clients allow for the concatenation
+	// of the registry host, "/" and <name>. The specification does not limit
+	// names, so this is a client heuristic that Validate does not enforce.
+	NameMaxLength = 255
+)
+
+var (
+	// NameRegexp is the anchored form of NamePattern.
+	NameRegexp = regexp.MustCompile(`^` + NamePattern + `$`)
+
+	// TagRegexp is the anchored form of TagPattern.
+	TagRegexp = regexp.MustCompile(`^` + TagPattern + `$`)
+
+	// DigestRegexp is the anchored form of DigestPattern.
+	DigestRegexp = regexp.MustCompile(`^` + DigestPattern + `$`)
+
+	nameComponentRegexp = regexp.MustCompile(`^` + NameComponentPattern + `$`)
+	tagCharsRegexp      = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]*$`)
+	hexRegexp           = regexp.MustCompile(`^[a-f0-9]+$`)
+
+	// encodedLengths lists the encoded length of registered algorithms.
+	encodedLengths = map[string]int{
+		"sha256": 64,
+		"sha512": 128,
+	}
+)
+
+// Error describes a single rule of the specification that a value breaks.
+type Error struct {
+	// Code is the error code a registry returns for this violation, one of
+	// NAME_INVALID, TAG_INVALID or DIGEST_INVALID.
+	Code v1.ErrorCode
+
+	// Value is the offending value.
+	Value string
+
+	// Reason describes the rule that is broken.
+	Reason string
+}
+
+// Error implements the Error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %q %s", e.Code, e.Value, e.Reason)
+}
+
+// Unwrap returns the error code so that errors.Is(err, v1.ErrorCodeNameInvalid)
+// can be used on the result of a validation.
+func (e *Error) Unwrap() error {
+	return e.Code
+}
+
+// ErrorList is returned when one or more rules are broken.
+type ErrorList []*Error
+
+// Error implements the Error interface.
+func (el ErrorList) Error() string {
+	msgs := make([]string, 0, len(el))
+	for _, e := range el {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any error in the list has the target error code.
+func (el ErrorList) Is(target error) bool {
+	for _, e := range el {
+		if e.Code == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Codes returns the distinct error codes in the list, in order.
+func (el ErrorList) Codes() []v1.ErrorCode {
+	var codes []v1.ErrorCode
+	seen := map[v1.ErrorCode]bool{}
+	for _, e := range el {
+		if !seen[e.Code] {
+			seen[e.Code] = true
+			codes = append(codes, e.Code)
+		}
+	}
+	return codes
+}
+
+func (el ErrorList) err() error {
+	if len(el) == 0 {
+		return nil
+	}
+	return el
+}
+
+// Reference is a parsed "registry/name:tag@digest" string.
+type Reference struct {
+	// Registry is the registry host and optional port. It may be empty.
+	Registry string
+
+	// Name is the repository name.
+	Name string
+
+	// Tag is the tag, if present.
+	Tag string
+
+	// Digest is the digest, if present.
+	Digest string
+}
+
+// String returns the reference in its "registry/name:tag@digest" form.
+func (r Reference) String() string {
+	s := r.Name
+	if r.Registry != "" {
+		s = r.Registry + "/" + s
+	}
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Ref returns the value to use as <reference> in a manifests request: the
+// digest when present, the tag otherwise.
+func (r Reference) Ref() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Validate checks every part of the reference and returns an ErrorList
+// holding all the rules that are broken.
+func (r Reference) Validate() error {
+	var errs ErrorList
+	errs = append(errs, nameErrors(r.Name)...)
+	if r.Tag != "" {
+		errs = append(errs, tagErrors(r.Tag)...)
+	}
+	if r.Digest != "" {
+		errs = append(errs, digestErrors(r.Digest)...)
+	}
+	return errs.err()
+}
+
+// Parse splits a "registry/name:tag@digest" string into a Reference and
+// validates it. The registry, tag and digest are optional. The first path
+// component is taken as registry when it contains a "." or ":", or is
+// "localhost". The parsed Reference is returned along with any validation
+// error so callers can report on every part of the input.
+func Parse(s string) (Reference, error) {
+	var (
+		r    Reference
+		errs ErrorList
+	)
+	rest := s
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		r.Digest = rest[i+1:]
+		rest = rest[:i]
+		if r.Digest == "" {
+			errs = append(errs, &Error{Code: v1.ErrorCodeDigestInvalid, Value: s, Reason: "has an empty digest after \"@\""})
+		}
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		r.Tag = rest[i+1:]
+		rest = rest[:i]
+		if r.Tag == "" {
+			errs = append(errs, &Error{Code: v1.ErrorCodeTagInvalid, Value: s, Reason: "has an empty tag after \":\""})
+		}
+	}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		first := rest[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			r.Registry = first
+			rest = rest[i+1:]
+		}
+	}
+	r.Name = rest
+	if err := r.Validate(); err != nil {
+		errs = append(errs, err.(ErrorList)...)
+	}
+	return r, errs.err()
+}
+
+// ValidateName checks name against the <name> grammar.
+func ValidateName(name string) error {
+	return ErrorList(nameErrors(name)).err()
+}
+
+// ValidateTag checks tag against the <reference> tag grammar.
+func ValidateTag(tag string) error {
+	return ErrorList(tagErrors(tag)).err()
+}
+
+// ValidateDigest checks digest against the digest grammar, including the
+// encoded length of the sha256 and sha512 algorithms.
+func ValidateDigest(digest string) error {
+	return ErrorList(digestErrors(digest)).err()
+}
+
+func nameErrors(name string) []*Error {
+	newErr := func(reason string, args ...interface{}) *Error {
+		return &Error{Code: v1.ErrorCodeNameInvalid, Value: name, Reason: fmt.Sprintf(reason, args...)}
+	}
+	if name == "" {
+		return []*Error{newErr("is empty")}
+	}
+	var errs []*Error
+	if strings.ToLower(name) != name {
+		errs = append(errs, newErr("contains uppercase characters"))
+	}
+	for _, c := range strings.Split(name, "/") {
+		lower := strings.ToLower(c)
+		switch {
+		case c == "":
+			errs = append(errs, newErr("contains an empty path component"))
+		case c == "." || c == "..":
+			errs = append(errs, newErr("contains the path component %q", c))
+		case strings.IndexAny(lower[:1], "._-") == 0:
+			errs = append(errs, newErr("has path component %q starting with a separator", c))
+		case strings.IndexAny(lower[len(lower)-1:], "._-") == 0:
+			errs = append(errs, newErr("has path component %q ending with a separator", c))
+		case !nameComponentRegexp.MatchString(lower):
+			errs = append(errs, newErr("has path component %q not matching %s", c, NameComponentPattern))
+		}
+	}
+	return errs
+}
+
+func tagErrors(tag string) []*Error {
+	newErr := func(reason string, args ...interface{}) *Error {
+		return &Error{Code: v1.ErrorCodeTagInvalid, Value: tag, Reason: fmt.Sprintf(reason, args...)}
+	}
+	if tag == "" {
+		return []*Error{newErr("is empty")}
+	}
+	var errs []*Error
+	if len(tag) > TagMaxLength {
+		errs = append(errs, newErr("is longer than %d characters", TagMaxLength))
+	}
+	if !tagCharsRegexp.MatchString(tag) {
+		errs = append(errs, newErr("does not match %s", TagPattern))
+	}
+	return errs
+}
+
+func digestErrors(digest string) []*Error {
+	newErr := func(reason string, args ...interface{}) *Error {
+		return &Error{Code: v1.ErrorCodeDigestInvalid, Value: digest, Reason: fmt.Sprintf(reason, args...)}
+	}
+	if !DigestRegexp.MatchString(digest) {
+		return []*Error{newErr("does not match %s", DigestPattern)}
+	}
+	alg, encoded, _ := strings.Cut(digest, ":")
+	if n, ok := encodedLengths[alg]; ok {
+		if len(encoded) != n {
+			return []*Error{newErr("must have %d characters after %q", n, alg+":")}
+		}
+		if !hexRegexp.MatchString(encoded) {
+			return []*Error{newErr("must be lowercase hex encoded")}
+		}
+	}
+	return nil
+}
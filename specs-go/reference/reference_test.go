@@ -0,0 +1,206 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reference
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"myrepo", false},
+		{"myorg/myrepo", false},
+		{"a/b/c/d/e/f/g", false},
+		{"my.repo", false},
+		{"my_repo", false},
+		{"my__repo", false},
+		{"my-repo", false},
+		{"my---repo", false},
+		{"0/1", false},
+		{strings.Repeat("a", NameMaxLength), false},
+		{strings.Repeat("a", NameMaxLength+1), false},
+		{"", true},
+		{"MyRepo", true},
+		{"myorg/MyRepo", true},
+		{"-myrepo", true},
+		{"myrepo-", true},
+		{".myrepo", true},
+		{"_myrepo", true},
+		{"my___repo", true},
+		{"my..repo", true},
+		{"my._repo", true},
+		{"myorg//myrepo", true},
+		{"/myrepo", true},
+		{"myrepo/", true},
+		{"myorg/../myrepo", true},
+		{"..", true},
+		{"my repo", true},
+		{"my:repo", true},
+	}
+	for _, tt := range tests {
+		err := ValidateName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, v1.ErrorCodeNameInvalid) {
+			t.Errorf("ValidateName(%q) error = %v, want NAME_INVALID", tt.name, err)
+		}
+		if got := NameRegexp.MatchString(tt.name); got == tt.wantErr {
+			t.Errorf("NameRegexp.MatchString(%q) = %v, want %v", tt.name, got, !tt.wantErr)
+		}
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		wantErr bool
+	}{
+		{"latest", false},
+		{"tagtest0", false},
+		{"TEST0", false},
+		{"_tag", false},
+		{"v1.0.0-rc.1", false},
+		{"a__b--c..d", false},
+		{strings.Repeat("a", TagMaxLength), false},
+		{"", true},
+		{".tag", true},
+		{"-tag", true},
+		{"tag+build", true},
+		{"tag/sub", true},
+		{"tag:sub", true},
+		{strings.Repeat("a", TagMaxLength+1), true},
+	}
+	for _, tt := range tests {
+		err := ValidateTag(tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTag(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, v1.ErrorCodeTagInvalid) {
+			t.Errorf("ValidateTag(%q) error = %v, want TAG_INVALID", tt.tag, err)
+		}
+		if got := TagRegexp.MatchString(tt.tag); got == tt.wantErr {
+			t.Errorf("TagRegexp.MatchString(%q) = %v, want %v", tt.tag, got, !tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDigest(t *testing.T) {
+	tests := []struct {
+		digest  string
+		wantErr bool
+	}{
+		{"sha256:" + strings.Repeat("a", 64), false},
+		{"sha512:" + strings.Repeat("a", 128), false},
+		{"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", false},
+		{"test+algorithm+using+algorithm+separators+and+lots+of+characters+to+excercise+overall+truncation:alsoSome=InTheEncodedSectionToShowHyphenReplacementAndLotsAndLotsOfCharactersToExcerciseEncodedTruncation", false},
+		{"sha256:totallywrong", true},
+		{"sha256:" + strings.Repeat("A", 64), true},
+		{"sha256:" + strings.Repeat("a", 63), true},
+		{"sha512:" + strings.Repeat("a", 64), true},
+		{"sha256", true},
+		{":abc", true},
+		{"SHA256:abc", true},
+	}
+	for _, tt := range tests {
+		err := ValidateDigest(tt.digest)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateDigest(%q) error = %v, wantErr %v", tt.digest, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, v1.ErrorCodeDigestInvalid) {
+			t.Errorf("ValidateDigest(%q) error = %v, want DIGEST_INVALID", tt.digest, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		in        string
+		want      Reference
+		wantCodes []v1.ErrorCode
+	}{
+		{
+			in:   "myorg/myrepo",
+			want: Reference{Name: "myorg/myrepo"},
+		},
+		{
+			in:   "myorg/myrepo:latest",
+			want: Reference{Name: "myorg/myrepo", Tag: "latest"},
+		},
+		{
+			in:   "registry.example.org:5000/myorg/myrepo:latest@" + digest,
+			want: Reference{Registry: "registry.example.org:5000", Name: "myorg/myrepo", Tag: "latest", Digest: digest},
+		},
+		{
+			in:   "localhost/myrepo@" + digest,
+			want: Reference{Registry: "localhost", Name: "myrepo", Digest: digest},
+		},
+		{
+			in:        "r.example.org/MyRepo:" + strings.Repeat("a", TagMaxLength+1),
+			want:      Reference{Registry: "r.example.org", Name: "MyRepo", Tag: strings.Repeat("a", TagMaxLength+1)},
+			wantCodes: []v1.ErrorCode{v1.ErrorCodeNameInvalid, v1.ErrorCodeTagInvalid},
+		},
+		{
+			in:        "myrepo:.tag@sha256:abc",
+			want:      Reference{Name: "myrepo", Tag: ".tag", Digest: "sha256:abc"},
+			wantCodes: []v1.ErrorCode{v1.ErrorCodeTagInvalid, v1.ErrorCodeDigestInvalid},
+		},
+		{
+			in:        "myrepo:",
+			want:      Reference{Name: "myrepo"},
+			wantCodes: []v1.ErrorCode{v1.ErrorCodeTagInvalid},
+		},
+		{
+			in:        "localhost/MyRepo:v1@",
+			want:      Reference{Registry: "localhost", Name: "MyRepo", Tag: "v1"},
+			wantCodes: []v1.ErrorCode{v1.ErrorCodeDigestInvalid, v1.ErrorCodeNameInvalid},
+		},
+		{
+			in:   "r.example.org:5000/" + strings.Repeat("a", NameMaxLength-10),
+			want: Reference{Registry: "r.example.org:5000", Name: strings.Repeat("a", NameMaxLength-10)},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+		var codes []v1.ErrorCode
+		var el ErrorList
+		if errors.As(err, &el) {
+			codes = el.Codes()
+		} else if err != nil {
+			t.Errorf("Parse(%q) error = %v, want ErrorList", tt.in, err)
+		}
+		if !reflect.DeepEqual(codes, tt.wantCodes) {
+			t.Errorf("Parse(%q) codes = %v, want %v", tt.in, codes, tt.wantCodes)
+		}
+		if err == nil && got.String() != tt.in {
+			t.Errorf("Parse(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package referrers implements the fallback for registries without the
+// referrers API, described in /spec.md#unavailable-referrers-api.
+package referrers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/opencontainers/distribution-spec/specs-go/endpoint"
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+const (
+	// MaxAlgorithmLength is the length the algorithm of a digest is
+	// truncated to in a referrers tag.
+	MaxAlgorithmLength = 32
+
+	// MaxEncodedLength is the length the encoded section of a digest is
+	// truncated to in a referrers tag.
+	MaxEncodedLength = 64
+)
+
+// ErrConflict is returned by Update when the fallback index was changed by
+// another client between reading and writing it.
+var ErrConflict = errors.New("referrers: fallback index was modified concurrently")
+
+// Tag returns the referrers tag of a digest, as defined in
+// /spec.md#referrers-tag-schema.
+func Tag(digest string) (string, error) {
+	i := strings.IndexByte(digest, ':')
+	if i <= 0 || i == len(digest)-1 {
+		return "", fmt.Errorf("referrers: invalid digest %q", digest)
+	}
+	alg, encoded := digest[:i], digest[i+1:]
+	if len(alg) > MaxAlgorithmLength {
+		alg = alg[:MaxAlgorithmLength]
+	}
+	if len(encoded) > MaxEncodedLength {
+		encoded = encoded[:MaxEncodedLength]
+	}
+	return strings.Map(tagChar, alg+"-"+encoded), nil
+}
+
+// tagChar replaces the characters not allowed in tags with '-'.
+func tagChar(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+		return r
+	}
+	return '-'
+}
+
+// Add adds d to the fallback index unless a descriptor with the same digest
+// is already listed. It reports whether the index changed.
+func Add(index *v1.ReferrersResponse, d v1.Descriptor) bool {
+	for _, m := range index.Manifests {
+		if m.Digest == d.Digest {
+			return false
+		}
+	}
+	index.Manifests = append(index.Manifests, d)
+	return true
+}
+
+// Remove removes the descriptors with the given digest from the fallback
+// index. It reports whether the index changed.
+func Remove(index *v1.ReferrersResponse, digest string) bool {
+	manifests := index.Manifests[:0]
+	for _, m := range index.Manifests {
+		if m.Digest != digest {
+			manifests = append(manifests, m)
+		}
+	}
+	changed := len(manifests) != len(index.Manifests)
+	index.Manifests = manifests
+	return changed
+}
+
+// Doer sends HTTP requests. *http.Client implements Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client reads and updates the fallback indexes of a repository.
+type Client struct {
+	// Doer sends the requests.
+	Doer Doer
+
+	// BaseURL is the scheme and host of the registry, such as
+	// "https://registry.example.org".
+	BaseURL string
+
+	// Name is the repository name.
+	Name string
+
+	// Header is added to every request, for instance to authenticate.
+	Header http.Header
+}
+
+func (c *Client) newRequest(method, tag string, body []byte) (*http.Request, error) {
+	path, err := endpoint.MustLookup(endpoint.End3).Path(map[string]string{"name": c.Name, "reference": tag}, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = append([]string{}, v...)
+	}
+	return req, nil
+}
+
+// Get returns the fallback index of subject along with its ETag, if the
+// registry sent one. An empty index is returned when the tag does not exist.
+func (c *Client) Get(subject string) (*v1.ReferrersResponse, string, error) {
+	tag, err := Tag(subject)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := c.newRequest(http.MethodGet, tag, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", v1.MediaTypeImageIndex)
+	resp, err := c.Doer.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return v1.NewReferrersResponse(), "", nil
+	default:
+		return nil, "", statusError(req, resp, body)
+	}
+	index := v1.NewReferrersResponse()
+	if err := json.Unmarshal(body, index); err != nil {
+		return nil, "", fmt.Errorf("referrers: decoding fallback index %s: %w", tag, err)
+	}
+	return index, resp.Header.Get("ETag"), nil
+}
+
+// Put pushes index as the fallback index of subject. When etag is not
+// empty, the push is conditional and ErrConflict is returned if the index
+// changed since it was read.
+func (c *Client) Put(subject string, index *v1.ReferrersResponse, etag string) error {
+	tag, err := Tag(subject)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(http.MethodPut, tag, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", v1.MediaTypeImageIndex)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := c.Doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return ErrConflict
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return statusError(req, resp, respBody)
+	}
+	return nil
+}
+
+// Update reads the fallback index of subject, passes it to update and pushes
+// the result if update reports a change. The push is conditional when the
+// registry sent an ETag.
+func (c *Client) Update(subject string, update func(index *v1.ReferrersResponse) bool) (*v1.ReferrersResponse, error) {
+	index, etag, err := c.Get(subject)
+	if err != nil {
+		return nil, err
+	}
+	if !update(index) {
+		return index, nil
+	}
+	if err := c.Put(subject, index, etag); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func statusError(req *http.Request, resp *http.Response, body []byte) error {
+	errResp := &v1.ErrorResponse{}
+	if err := json.Unmarshal(body, errResp); err == nil && len(errResp.Errors) > 0 {
+		return fmt.Errorf("referrers: %s %s returned %d: %w", req.Method, req.URL, resp.StatusCode, errResp)
+	}
+	return fmt.Errorf("referrers: %s %s returned %d", req.Method, req.URL, resp.StatusCode)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package referrers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "github.com/opencontainers/distribution-spec/specs-go/v1"
+)
+
+func TestTag(t *testing.T) {
+	// examples from /spec.md#referrers-tag-schema
+	a64 := strings.Repeat("a", 64)
+	tests := []struct {
+		digest string
+		want   string
+	}{
+		{"sha256:" + a64, "sha256-" + a64},
+		{"sha512:" + a64 + a64, "sha512-" + a64},
+		{
+			"test+algorithm+using+algorithm+separators+and+lots+of+characters+to+excercise+overall+truncation:alsoSome=InTheEncodedSectionToShowHyphenReplacementAndLotsAndLotsOfCharactersToExcerciseEncodedTruncation",
+			"test-algorithm-using-algorithm-s-alsoSome-InTheEncodedSectionToShowHyphenReplacementAndLotsAndLot",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Tag(tt.digest)
+		if err != nil || got != tt.want {
+			t.Errorf("Tag(%q) = %q, %v, want %q", tt.digest, got, err, tt.want)
+		}
+	}
+	for _, digest := range []string{"", "sha256", ":abc", "sha256:"} {
+		if _, err := Tag(digest); err == nil {
+			t.Errorf("Tag(%q) should fail", digest)
+		}
+	}
+}
+
+// indexServer stores a single manifest per tag and honors If-Match.
+func indexServer() *httptest.Server {
+	manifests := map[string][]byte{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := strconv.Quote(strconv.Itoa(len(manifests[r.URL.Path])))
+		switch r.Method {
+		case http.MethodGet:
+			body, ok := manifests[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write(body)
+		case http.MethodPut:
+			if m := r.Header.Get("If-Match"); m != "" && m != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			manifests[r.URL.Path], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+}
+
+func TestUpdate(t *testing.T) {
+	srv := indexServer()
+	defer srv.Close()
+	c := &Client{Doer: srv.Client(), BaseURL: srv.URL, Name: "myorg/myrepo"}
+	subject := "sha256:" + strings.Repeat("b", 64)
+	a := v1.Descriptor{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: "sha256:" + strings.Repeat("1", 64), Size: 10}
+	b := v1.Descriptor{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: "sha256:" + strings.Repeat("2", 64), Size: 20}
+
+	for _, d := range []v1.Descriptor{a, b, a} {
+		if _, err := c.Update(subject, func(index *v1.ReferrersResponse) bool { return Add(index, d) }); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	index, etag, err := c.Get(subject)
+	if err != nil || len(index.Manifests) != 2 || index.Manifests[1].Digest != b.Digest {
+		t.Fatalf("Get() = %v, %v", index, err)
+	}
+	if !Remove(index, a.Digest) || Remove(index, a.Digest) {
+		t.Errorf("Remove() should only report the first removal")
+	}
+	if err := c.Put(subject, index, `"stale"`); !errors.Is(err, ErrConflict) {
+		t.Errorf("Put() with stale ETag error = %v, want %v", err, ErrConflict)
+	}
+	if err := c.Put(subject, index, etag); err != nil {
+		t.Errorf("Put() error = %v", err)
+	}
+}
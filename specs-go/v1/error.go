@@ -14,6 +14,139 @@
 
 package v1
 
+import (
+	"net/http"
+	"strings"
+)
+
+// ErrorCode is the identifier found in the code field of an ErrorInfo.
+type ErrorCode string
+
+// Error codes defined in the "Error Codes" section of the specification.
+const (
+	// ErrorCodeBlobUnknown is code-1.
+	ErrorCodeBlobUnknown ErrorCode = "BLOB_UNKNOWN"
+	// ErrorCodeBlobUploadInvalid is code-2.
+	ErrorCodeBlobUploadInvalid ErrorCode = "BLOB_UPLOAD_INVALID"
+	// ErrorCodeBlobUploadUnknown is code-3.
+	ErrorCodeBlobUploadUnknown ErrorCode = "BLOB_UPLOAD_UNKNOWN"
+	// ErrorCodeDigestInvalid is code-4.
+	ErrorCodeDigestInvalid ErrorCode = "DIGEST_INVALID"
+	// ErrorCodeManifestBlobUnknown is code-5.
+	ErrorCodeManifestBlobUnknown ErrorCode = "MANIFEST_BLOB_UNKNOWN"
+	// ErrorCodeManifestInvalid is code-6.
+	ErrorCodeManifestInvalid ErrorCode = "MANIFEST_INVALID"
+	// ErrorCodeManifestUnknown is code-7.
+	ErrorCodeManifestUnknown ErrorCode = "MANIFEST_UNKNOWN"
+	// ErrorCodeNameInvalid is code-8.
+	ErrorCodeNameInvalid ErrorCode = "NAME_INVALID"
+	// ErrorCodeNameUnknown is code-9.
+	ErrorCodeNameUnknown ErrorCode = "NAME_UNKNOWN"
+	// ErrorCodeSizeInvalid is code-10.
+	ErrorCodeSizeInvalid ErrorCode = "SIZE_INVALID"
+	// ErrorCodeUnauthorized is code-11.
+	ErrorCodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// ErrorCodeDenied is code-12.
+	ErrorCodeDenied ErrorCode = "DENIED"
+	// ErrorCodeUnsupported is code-13.
+	ErrorCodeUnsupported ErrorCode = "UNSUPPORTED"
+	// ErrorCodeTooManyRequests is code-14.
+	ErrorCodeTooManyRequests ErrorCode = "TOOMANYREQUESTS"
+)
+
+// Legacy Docker error codes. Clients may encounter these but should not
+// depend on them.
+const (
+	// ErrorCodeTagInvalid is returned for a malformed tag reference.
+	ErrorCodeTagInvalid ErrorCode = "TAG_INVALID"
+	// ErrorCodeManifestUnverified is returned for a schema1 manifest whose
+	// signature could not be verified.
+	ErrorCodeManifestUnverified ErrorCode = "MANIFEST_UNVERIFIED"
+)
+
+type errorCodeInfo struct {
+	id          string
+	description string
+	status      int
+}
+
+var errorCodeTable = map[ErrorCode]errorCodeInfo{
+	ErrorCodeBlobUnknown:         {"code-1", "blob unknown to registry", http.StatusNotFound},
+	ErrorCodeBlobUploadInvalid:   {"code-2", "blob upload invalid", http.StatusBadRequest},
+	ErrorCodeBlobUploadUnknown:   {"code-3", "blob upload unknown to registry", http.StatusNotFound},
+	ErrorCodeDigestInvalid:       {"code-4", "provided digest did not match uploaded content", http.StatusBadRequest},
+	ErrorCodeManifestBlobUnknown: {"code-5", "manifest references a manifest or blob unknown to registry", http.StatusBadRequest},
+	ErrorCodeManifestInvalid:     {"code-6", "manifest invalid", http.StatusBadRequest},
+	ErrorCodeManifestUnknown:     {"code-7", "manifest unknown to registry", http.StatusNotFound},
+	ErrorCodeNameInvalid:         {"code-8", "invalid repository name", http.StatusBadRequest},
+	ErrorCodeNameUnknown:         {"code-9", "repository name not known to registry", http.StatusNotFound},
+	ErrorCodeSizeInvalid:         {"code-10", "provided length did not match content length", http.StatusBadRequest},
+	ErrorCodeUnauthorized:        {"code-11", "authentication required", http.StatusUnauthorized},
+	ErrorCodeDenied:              {"code-12", "requested access to the resource is denied", http.StatusForbidden},
+	ErrorCodeUnsupported:         {"code-13", "the operation is unsupported", http.StatusMethodNotAllowed},
+	ErrorCodeTooManyRequests:     {"code-14", "too many requests", http.StatusTooManyRequests},
+	ErrorCodeTagInvalid:          {"", "manifest tag did not match URI", http.StatusBadRequest},
+	ErrorCodeManifestUnverified:  {"", "manifest failed signature verification", http.StatusBadRequest},
+}
+
+// ErrorCodes returns the error codes defined by the specification, in the
+// order of their IDs (code-1 through code-14). Legacy codes are not included.
+func ErrorCodes() []ErrorCode {
+	return []ErrorCode{
+		ErrorCodeBlobUnknown,
+		ErrorCodeBlobUploadInvalid,
+		ErrorCodeBlobUploadUnknown,
+		ErrorCodeDigestInvalid,
+		ErrorCodeManifestBlobUnknown,
+		ErrorCodeManifestInvalid,
+		ErrorCodeManifestUnknown,
+		ErrorCodeNameInvalid,
+		ErrorCodeNameUnknown,
+		ErrorCodeSizeInvalid,
+		ErrorCodeUnauthorized,
+		ErrorCodeDenied,
+		ErrorCodeUnsupported,
+		ErrorCodeTooManyRequests,
+	}
+}
+
+// ID returns the identifier of the code in the specification, such as
+// "code-1". It is empty for legacy and unknown codes.
+func (ec ErrorCode) ID() string {
+	return errorCodeTable[ec].id
+}
+
+// Description returns the description of the code from the specification.
+// It is empty for unknown codes.
+func (ec ErrorCode) Description() string {
+	return errorCodeTable[ec].description
+}
+
+// HTTPStatus returns the HTTP status a registry is expected to send along
+// with the error code, following the failure codes of the endpoints that
+// return it. Unknown codes map to 400 Bad Request.
+func (ec ErrorCode) HTTPStatus() int {
+	if info, ok := errorCodeTable[ec]; ok {
+		return info.status
+	}
+	return http.StatusBadRequest
+}
+
+// Known reports whether the code is defined by the specification, including
+// the legacy codes.
+func (ec ErrorCode) Known() bool {
+	_, ok := errorCodeTable[ec]
+	return ok
+}
+
+// Error implements the Error interface.
+func (ec ErrorCode) Error() string {
+	if d := ec.Description(); d != "" {
+		return string(ec) + ": " + d
+	}
+	return string(ec)
+}
+
 // ErrorResponse is returned by a registry on an invalid request.
 type ErrorResponse struct {
 	Errors []ErrorInfo `json:"errors"`
@@ -22,9 +155,17 @@ type ErrorResponse struct {
 // ErrRegistry is the string returned by and ErrorResponse error.
 var ErrRegistry = "distribution: registry returned error"
 
-// Error implements the Error interface.
+// Error implements the Error interface. The returned string lists the code
+// and message of every ErrorInfo in the response.
 func (er *ErrorResponse) Error() string {
-	return ErrRegistry
+	if len(er.Errors) == 0 {
+		return ErrRegistry
+	}
+	parts := make([]string, 0, len(er.Errors))
+	for _, e := range er.Errors {
+		parts = append(parts, e.Error())
+	}
+	return ErrRegistry + ": " + strings.Join(parts, "; ")
 }
 
 // Detail returns an ErrorInfo
@@ -32,9 +173,64 @@ func (er *ErrorResponse) Detail() []ErrorInfo {
 	return er.Errors
 }
 
+// Codes returns the error codes contained in the response, in order.
+func (er *ErrorResponse) Codes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(er.Errors))
+	for _, e := range er.Errors {
+		codes = append(codes, ErrorCode(e.Code))
+	}
+	return codes
+}
+
+// Is reports whether the response contains the target ErrorCode. It allows
+// errors.Is(err, ErrorCodeBlobUnknown) on a returned ErrorResponse.
+func (er *ErrorResponse) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	if !ok {
+		return false
+	}
+	for _, e := range er.Errors {
+		if ErrorCode(e.Code) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets target to the first error in the response when target is an
+// *ErrorCode or *ErrorInfo.
+func (er *ErrorResponse) As(target interface{}) bool {
+	if len(er.Errors) == 0 {
+		return false
+	}
+	switch t := target.(type) {
+	case *ErrorCode:
+		*t = ErrorCode(er.Errors[0].Code)
+		return true
+	case *ErrorInfo:
+		*t = er.Errors[0]
+		return true
+	}
+	return false
+}
+
 // ErrorInfo describes a server error returned from a registry.
 type ErrorInfo struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Detail  string `json:"detail"`
 }
+
+// Error implements the Error interface.
+func (ei ErrorInfo) Error() string {
+	if ei.Message == "" {
+		return ei.Code
+	}
+	return ei.Code + ": " + ei.Message
+}
+
+// Is reports whether the target is the ErrorCode of the ErrorInfo.
+func (ei ErrorInfo) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && ErrorCode(ei.Code) == code
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestErrorCodeHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code ErrorCode
+		want int
+	}{
+		{ErrorCodeBlobUnknown, http.StatusNotFound},
+		{ErrorCodeDigestInvalid, http.StatusBadRequest},
+		{ErrorCodeManifestUnknown, http.StatusNotFound},
+		{ErrorCodeUnauthorized, http.StatusUnauthorized},
+		{ErrorCodeDenied, http.StatusForbidden},
+		{ErrorCodeUnsupported, http.StatusMethodNotAllowed},
+		{ErrorCodeTooManyRequests, http.StatusTooManyRequests},
+		{ErrorCodeTagInvalid, http.StatusBadRequest},
+		{ErrorCode("UNKNOWN_CODE"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := tt.code.HTTPStatus(); got != tt.want {
+			t.Errorf("%s.HTTPStatus() = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestErrorResponseError(t *testing.T) {
+	tests := []struct {
+		name string
+		er   ErrorResponse
+		want string
+	}{
+		{"empty", ErrorResponse{}, ErrRegistry},
+		{"one", ErrorResponse{Errors: []ErrorInfo{{Code: "BLOB_UNKNOWN", Message: "blob unknown to registry"}}},
+			ErrRegistry + ": BLOB_UNKNOWN: blob unknown to registry"},
+		{"several", ErrorResponse{Errors: []ErrorInfo{{Code: "NAME_INVALID", Message: "invalid name"}, {Code: "DENIED"}}},
+			ErrRegistry + ": NAME_INVALID: invalid name; DENIED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.er.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorResponseIs(t *testing.T) {
+	var err error = &ErrorResponse{Errors: []ErrorInfo{{Code: "NAME_UNKNOWN"}, {Code: "DENIED"}}}
+	tests := []struct {
+		target error
+		want   bool
+	}{
+		{ErrorCodeNameUnknown, true},
+		{ErrorCodeDenied, true},
+		{ErrorCodeBlobUnknown, false},
+		{errors.New("NAME_UNKNOWN"), false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+	if wrapped := fmt.Errorf("pull: %w", err); !errors.Is(wrapped, ErrorCodeDenied) {
+		t.Errorf("errors.Is on a wrapped response = false, want true")
+	}
+	if errors.Is(&ErrorResponse{}, ErrorCodeDenied) {
+		t.Errorf("errors.Is on an empty response = true, want false")
+	}
+}
+
+func TestErrorResponseAs(t *testing.T) {
+	var err error = &ErrorResponse{Errors: []ErrorInfo{
+		{Code: "MANIFEST_UNKNOWN", Message: "manifest unknown", Detail: "latest"},
+		{Code: "DENIED"},
+	}}
+
+	var code ErrorCode
+	if !errors.As(err, &code) || code != ErrorCodeManifestUnknown {
+		t.Errorf("errors.As(*ErrorCode) = %q, want %q", code, ErrorCodeManifestUnknown)
+	}
+	var info ErrorInfo
+	if !errors.As(err, &info) || info.Detail != "latest" {
+		t.Errorf("errors.As(*ErrorInfo) = %+v, want the first error", info)
+	}
+	var response *ErrorResponse
+	if !errors.As(err, &response) || len(response.Errors) != 2 {
+		t.Errorf("errors.As(**ErrorResponse) = %v, want the response", response)
+	}
+
+	if (&ErrorResponse{}).As(&code) {
+		t.Errorf("As on an empty response = true, want false")
+	}
+	var s string
+	if (&ErrorResponse{Errors: []ErrorInfo{{Code: "DENIED"}}}).As(&s) {
+		t.Errorf("As(*string) = true, want false")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "strings"
+
+const (
+	// MediaTypeImageIndex is the media type of the referrers response body.
+	MediaTypeImageIndex = "application/vnd.oci.image.index.v1+json"
+
+	// HeaderFiltersApplied is the response header listing the filters a
+	// registry applied to a referrers request.
+	HeaderFiltersApplied = "OCI-Filters-Applied"
+
+	// FilterArtifactType is the name of the artifactType filter, used both as
+	// query parameter and as value of the OCI-Filters-Applied header.
+	FilterArtifactType = "artifactType"
+)
+
+// Descriptor describes a manifest in the referrers list.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// ReferrersResponse is the image index returned by the referrers API
+// defined in /spec.md#listing-referrers.
+type ReferrersResponse struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+
+	// FiltersApplied holds the filters listed in the OCI-Filters-Applied
+	// response header. It is not part of the response body.
+	FiltersApplied []string `json:"-"`
+}
+
+// Index is an image index, the document type of the referrers response,
+// with the fields of an index pushed as a referrer.
+type Index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Manifests     []Descriptor      `json:"manifests"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// NewReferrersResponse returns a ReferrersResponse listing the given
+// descriptors.
+func NewReferrersResponse(manifests ...Descriptor) *ReferrersResponse {
+	if manifests == nil {
+		manifests = []Descriptor{}
+	}
+	return &ReferrersResponse{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageIndex,
+		Manifests:     manifests,
+	}
+}
+
+// ParseFiltersApplied splits the value of an OCI-Filters-Applied header into
+// the list of filter names.
+func ParseFiltersApplied(header string) []string {
+	var filters []string
+	for _, f := range strings.Split(header, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
+
+// FilterApplied reports whether the registry applied the named filter.
+func (rr *ReferrersResponse) FilterApplied(name string) bool {
+	for _, f := range rr.FiltersApplied {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterArtifactType returns the descriptors matching artifactType. When the
+// registry did not apply the artifactType filter, the filter is applied on
+// the client side. An empty artifactType matches every descriptor.
+func (rr *ReferrersResponse) FilterArtifactType(artifactType string) *ReferrersResponse {
+	if artifactType == "" || rr.FilterApplied(FilterArtifactType) {
+		return rr
+	}
+	filtered := &ReferrersResponse{
+		SchemaVersion:  rr.SchemaVersion,
+		MediaType:      rr.MediaType,
+		Manifests:      []Descriptor{},
+		FiltersApplied: rr.FiltersApplied,
+	}
+	for _, d := range rr.Manifests {
+		if d.ArtifactType == artifactType {
+			filtered.Manifests = append(filtered.Manifests, d)
+		}
+	}
+	return filtered
+}
+
+// MergeReferrersResponses combines the pages of a paginated referrers
+// response into a single response. Descriptors are kept in page order and
+// duplicates are dropped. A filter is reported as applied only if every page
+// applied it.
+func MergeReferrersResponses(pages ...*ReferrersResponse) *ReferrersResponse {
+	merged := NewReferrersResponse()
+	seen := map[string]bool{}
+	for i, page := range pages {
+		if i == 0 {
+			merged.FiltersApplied = append([]string{}, page.FiltersApplied...)
+		} else {
+			var common []string
+			for _, f := range merged.FiltersApplied {
+				if page.FilterApplied(f) {
+					common = append(common, f)
+				}
+			}
+			merged.FiltersApplied = common
+		}
+		for _, d := range page.Manifests {
+			if seen[d.Digest] {
+				continue
+			}
+			seen[d.Digest] = true
+			merged.Manifests = append(merged.Manifests, d)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	sbomA = Descriptor{Digest: "sha256:a", ArtifactType: "application/vnd.example.sbom"}
+	sbomB = Descriptor{Digest: "sha256:b", ArtifactType: "application/vnd.example.sbom"}
+	sigC  = Descriptor{Digest: "sha256:c", ArtifactType: "application/vnd.example.signature"}
+)
+
+func TestFilterArtifactType(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           *ReferrersResponse
+		artifactType string
+		want         []Descriptor
+	}{
+		{"empty type", NewReferrersResponse(sbomA, sigC), "", []Descriptor{sbomA, sigC}},
+		{"client side", NewReferrersResponse(sbomA, sigC, sbomB), sbomA.ArtifactType, []Descriptor{sbomA, sbomB}},
+		{"no match", NewReferrersResponse(sbomA, sbomB), sigC.ArtifactType, []Descriptor{}},
+		{"empty list", NewReferrersResponse(), sigC.ArtifactType, []Descriptor{}},
+		{"applied by registry", &ReferrersResponse{
+			Manifests:      []Descriptor{sbomA, sigC},
+			FiltersApplied: []string{FilterArtifactType},
+		}, sigC.ArtifactType, []Descriptor{sbomA, sigC}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in.FilterArtifactType(tt.artifactType)
+			if !reflect.DeepEqual(got.Manifests, tt.want) {
+				t.Errorf("FilterArtifactType(%q) = %v, want %v", tt.artifactType, got.Manifests, tt.want)
+			}
+			if !reflect.DeepEqual(got.FiltersApplied, tt.in.FiltersApplied) {
+				t.Errorf("FiltersApplied = %v, want %v", got.FiltersApplied, tt.in.FiltersApplied)
+			}
+		})
+	}
+}
+
+func TestMergeReferrersResponses(t *testing.T) {
+	page := func(filters []string, manifests ...Descriptor) *ReferrersResponse {
+		rr := NewReferrersResponse(manifests...)
+		rr.FiltersApplied = filters
+		return rr
+	}
+	tests := []struct {
+		name        string
+		pages       []*ReferrersResponse
+		want        []Descriptor
+		wantFilters []string
+	}{
+		{"no pages", nil, []Descriptor{}, nil},
+		{"one page", []*ReferrersResponse{page(nil, sbomA, sigC)}, []Descriptor{sbomA, sigC}, []string{}},
+		{"page order", []*ReferrersResponse{page(nil, sigC), page(nil, sbomB, sbomA)},
+			[]Descriptor{sigC, sbomB, sbomA}, nil},
+		{"duplicates across pages", []*ReferrersResponse{page(nil, sbomA, sbomB), page(nil, sbomB, sigC), page(nil, sbomA)},
+			[]Descriptor{sbomA, sbomB, sigC}, nil},
+		{"duplicates within a page", []*ReferrersResponse{page(nil, sbomA, sbomA)}, []Descriptor{sbomA}, []string{}},
+		{"filter on every page", []*ReferrersResponse{
+			page([]string{FilterArtifactType}, sbomA), page([]string{FilterArtifactType}, sbomB),
+		}, []Descriptor{sbomA, sbomB}, []string{FilterArtifactType}},
+		{"filter on some pages", []*ReferrersResponse{
+			page([]string{FilterArtifactType}, sbomA), page(nil, sigC),
+		}, []Descriptor{sbomA, sigC}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeReferrersResponses(tt.pages...)
+			if !reflect.DeepEqual(got.Manifests, tt.want) {
+				t.Errorf("Manifests = %v, want %v", got.Manifests, tt.want)
+			}
+			if !reflect.DeepEqual(got.FiltersApplied, tt.wantFilters) {
+				t.Errorf("FiltersApplied = %#v, want %#v", got.FiltersApplied, tt.wantFilters)
+			}
+			if got.SchemaVersion != 2 || got.MediaType != MediaTypeImageIndex {
+				t.Errorf("got schemaVersion %d and mediaType %q, want an image index", got.SchemaVersion, got.MediaType)
+			}
+		})
+	}
+}
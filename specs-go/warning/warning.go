@@ -0,0 +1,278 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package warning parses the Warning headers described in /spec.md#warnings.
+package warning
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Header is the name of the warning header.
+	Header = "Warning"
+
+	// Code is the only warn-code a registry may send.
+	Code = 299
+
+	// Agent is the only warn-agent a registry may send.
+	Agent = "-"
+
+	// MaxSize is the maximum number of bytes of warning data a registry may
+	// send in all headers of a response combined.
+	MaxSize = 4096
+)
+
+// Warning is a single warning-value of a Warning header.
+type Warning struct {
+	// Code is the warn-code.
+	Code int
+
+	// Agent is the warn-agent.
+	Agent string
+
+	// Text is the unquoted warn-text.
+	Text string
+
+	// Date is the unquoted warn-date. Registries must not send it.
+	Date string
+}
+
+// String returns the warning in header form, such as `299 - "text"`.
+func (w Warning) String() string {
+	s := fmt.Sprintf("%03d %s %s", w.Code, w.Agent, strconv.Quote(w.Text))
+	if w.Date != "" {
+		s += " " + strconv.Quote(w.Date)
+	}
+	return s
+}
+
+// Validate checks the warning against the rules of the specification.
+func (w Warning) Validate() error {
+	var errs ErrorList
+	if w.Code != Code {
+		errs = append(errs, &Error{Value: w.String(), Reason: fmt.Sprintf("has warn-code %03d, want %d", w.Code, Code)})
+	}
+	if w.Agent != Agent {
+		errs = append(errs, &Error{Value: w.String(), Reason: fmt.Sprintf("has warn-agent %q, want %q", w.Agent, Agent)})
+	}
+	if w.Date != "" {
+		errs = append(errs, &Error{Value: w.String(), Reason: "has a warn-date"})
+	}
+	return errs.err()
+}
+
+// Error describes a warning header that breaks a rule of the specification.
+type Error struct {
+	// Value is the offending header value or warning.
+	Value string
+
+	// Reason describes the rule that is broken.
+	Reason string
+}
+
+// Error implements the Error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("warning %q %s", e.Value, e.Reason)
+}
+
+// ErrorList is returned when one or more rules are broken.
+type ErrorList []*Error
+
+// Error implements the Error interface.
+func (el ErrorList) Error() string {
+	msgs := make([]string, 0, len(el))
+	for _, e := range el {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (el ErrorList) err() error {
+	if len(el) == 0 {
+		return nil
+	}
+	return el
+}
+
+// Parse parses the value of a single Warning header, which may hold several
+// comma separated warnings. Only syntax errors are returned; use Validate to
+// check each warning against the specification.
+func Parse(value string) ([]Warning, error) {
+	var warnings []Warning
+	s := strings.TrimSpace(value)
+	for s != "" {
+		var (
+			w   Warning
+			err error
+		)
+		w, s, err = parseOne(s)
+		if err != nil {
+			return warnings, &Error{Value: value, Reason: err.Error()}
+		}
+		warnings = append(warnings, w)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			break
+		}
+		if s[0] != ',' {
+			return warnings, &Error{Value: value, Reason: fmt.Sprintf("has unexpected %q after a warning", s)}
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+	return warnings, nil
+}
+
+func parseOne(s string) (Warning, string, error) {
+	var w Warning
+	if len(s) < 4 || s[3] != ' ' {
+		return w, s, fmt.Errorf("does not start with a 3 digit warn-code")
+	}
+	code, err := strconv.Atoi(s[:3])
+	if err != nil || code < 0 {
+		return w, s, fmt.Errorf("does not start with a 3 digit warn-code")
+	}
+	w.Code = code
+	s = s[4:]
+	i := strings.IndexByte(s, ' ')
+	if i <= 0 {
+		return w, s, fmt.Errorf("has no warn-agent")
+	}
+	w.Agent, s = s[:i], s[i+1:]
+	w.Text, s, err = parseQuoted(s)
+	if err != nil {
+		return w, s, fmt.Errorf("has an invalid warn-text: %v", err)
+	}
+	if strings.HasPrefix(s, " ") && strings.HasPrefix(strings.TrimLeft(s, " "), `"`) {
+		w.Date, s, err = parseQuoted(strings.TrimLeft(s, " "))
+		if err != nil {
+			return w, s, fmt.Errorf("has an invalid warn-date: %v", err)
+		}
+	}
+	return w, s, nil
+}
+
+func parseQuoted(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, fmt.Errorf("not a quoted-string")
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) {
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", s, fmt.Errorf("unterminated quoted-string")
+}
+
+// ParseHeader parses every Warning header in h. The returned error is an
+// ErrorList holding syntax errors, warnings that break the rules of the
+// specification, and a violation of the combined size limit.
+func ParseHeader(h http.Header) ([]Warning, error) {
+	var (
+		warnings []Warning
+		errs     ErrorList
+		size     int
+	)
+	for _, v := range h.Values(Header) {
+		size += len(v)
+		ws, err := Parse(v)
+		if err != nil {
+			errs = append(errs, err.(*Error))
+		}
+		for _, w := range ws {
+			if err := w.Validate(); err != nil {
+				errs = append(errs, err.(ErrorList)...)
+			}
+		}
+		warnings = append(warnings, ws...)
+	}
+	if size > MaxSize {
+		errs = append(errs, &Error{
+			Value:  fmt.Sprintf("%d bytes in %d headers", size, len(h.Values(Header))),
+			Reason: fmt.Sprintf("exceeds the limit of %d bytes", MaxSize),
+		})
+	}
+	return warnings, errs.err()
+}
+
+// Collector gathers the warnings of the responses of one operation, dropping
+// duplicates as clients should. It is safe for concurrent use.
+type Collector struct {
+	mu       sync.Mutex
+	warnings []Warning
+	errs     ErrorList
+	seen     map[string]bool
+}
+
+// NewCollector returns an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{seen: map[string]bool{}}
+}
+
+// Add records the warnings of a response header and returns the violations
+// found in it, if any.
+func (c *Collector) Add(h http.Header) error {
+	warnings, err := ParseHeader(h)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range warnings {
+		key := w.String()
+		if c.seen[key] {
+			continue
+		}
+		c.seen[key] = true
+		c.warnings = append(c.warnings, w)
+	}
+	if err != nil {
+		c.errs = append(c.errs, err.(ErrorList)...)
+	}
+	return err
+}
+
+// Warnings returns the distinct warnings collected so far, in the order they
+// were first received.
+func (c *Collector) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning{}, c.warnings...)
+}
+
+// Errors returns the violations found so far.
+func (c *Collector) Errors() ErrorList {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append(ErrorList{}, c.errs...)
+}
+
+// Reset forgets everything collected so far.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.warnings = nil
+	c.errs = nil
+	c.seen = map[string]bool{}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 The Linux Foundation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package warning
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []Warning
+		wantErr bool
+	}{
+		{
+			in:   []string{`299 - "this image is deprecated"`},
+			want: []Warning{{Code: 299, Agent: "-", Text: "this image is deprecated"}},
+		},
+		{
+			in: []string{`299 - "a, \"quoted\"", 299 - "b"`, `299 - "c"`},
+			want: []Warning{
+				{Code: 299, Agent: "-", Text: `a, "quoted"`},
+				{Code: 299, Agent: "-", Text: "b"},
+				{Code: 299, Agent: "-", Text: "c"},
+			},
+		},
+		{
+			in:      []string{`199 registry.example.org "text" "Sat, 25 Aug 2012 23:34:45 GMT"`},
+			want:    []Warning{{Code: 199, Agent: "registry.example.org", Text: "text", Date: "Sat, 25 Aug 2012 23:34:45 GMT"}},
+			wantErr: true,
+		},
+		{in: []string{`299 - text`}, wantErr: true},
+		{in: []string{`299 - "unterminated`}, wantErr: true},
+		{in: []string{`29 - "short code"`}, wantErr: true},
+		{in: []string{`299 - "` + strings.Repeat("x", MaxSize) + `"`}, want: []Warning{{Code: 299, Agent: "-", Text: strings.Repeat("x", MaxSize)}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		h := http.Header{Header: tt.in}
+		got, err := ParseHeader(h)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHeader(%.40q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseHeader(%.40q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	_ = c.Add(http.Header{Header: {`299 - "a"`, `299 - "b"`}})
+	_ = c.Add(http.Header{Header: {`299 - "a"`}})
+	if err := c.Add(http.Header{Header: {`110 - "stale"`}}); err == nil {
+		t.Errorf("Add() with warn-code 110 should fail")
+	}
+	if got := c.Warnings(); len(got) != 3 || got[0].Text != "a" || got[1].Text != "b" {
+		t.Errorf("Warnings() = %v", got)
+	}
+	if got := c.Errors(); len(got) != 1 {
+		t.Errorf("Errors() = %v", got)
+	}
+	c.Reset()
+	if len(c.Warnings()) != 0 || len(c.Errors()) != 0 {
+		t.Errorf("Reset() did not clear the collector")
+	}
+}